@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/mat-sik/eureka-go/internal/client"
+	"github.com/mat-sik/eureka-go/internal/health"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+type app struct {
+	client       client.Client
+	streamClient client.Client
+	httpClient   *http.Client
+	printer      printer
+}
+
+type command func(ctx context.Context, app app, args []string) error
+
+var commands = map[string]command{
+	"services ls":     servicesLs,
+	"instances ls":    instancesLs,
+	"register":        register,
+	"deregister":      deregister,
+	"drain":           drain,
+	"watch":           watch,
+	"graph":           graph,
+	"consumers":       consumers,
+	"kv get":          kvGet,
+	"kv put":          kvPut,
+	"kv del":          kvDel,
+	"kv ls":           kvLs,
+	"kv watch":        kvWatch,
+	"lock ls":         lockLs,
+	"lock get":        lockGet,
+	"lock acquire":    lockAcquire,
+	"lock release":    lockRelease,
+	"health check":    healthCheck,
+	"snapshot export": snapshotExport,
+	"snapshot import": snapshotImport,
+}
+
+func lookup(args []string) (command, []string, error) {
+	if len(args) >= 2 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return cmd, args[2:], nil
+		}
+	}
+	if len(args) >= 1 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd, args[1:], nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unknown command %q", strings.Join(args, " "))
+}
+
+func servicesLs(ctx context.Context, app app, _ []string) error {
+	services, err := app.client.Services(ctx)
+	if err != nil {
+		return err
+	}
+	return app.printer.services(services)
+}
+
+func instancesLs(ctx context.Context, app app, args []string) error {
+	flags := flag.NewFlagSet("instances ls", flag.ContinueOnError)
+	region := flags.String("region", "", "region to look up, the registry's own region when empty")
+	zone := flags.String("zone", "", "caller zone, ranks healthy instances in that zone first")
+	fallback := flags.Bool("fallback", false, "fall back to remote regions when no local instance is healthy")
+	strategy := flags.String("strategy", "", "selection strategy, weighted picks healthy instances by weight")
+	count := flags.Int("count", 0, "number of instances a strategy picks, one when zero")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: instances ls [-region R] [-zone Z] [-fallback] [-strategy weighted [-count N]] <serviceID>")
+	}
+
+	lookupResp, err := app.client.Lookup(ctx, flags.Arg(0), client.LookupOptions{
+		Region:         *region,
+		Zone:           *zone,
+		FallbackRemote: *fallback,
+		Strategy:       *strategy,
+		Count:          *count,
+	})
+	if err != nil {
+		return err
+	}
+	return app.printer.instances(lookupResp.HostStatuses)
+}
+
+func register(ctx context.Context, app app, args []string) error {
+	flags := flag.NewFlagSet("register", flag.ContinueOnError)
+	serviceID := flags.String("service", "", "service ID")
+	instanceID := flags.String("id", "", "instance ID, generated by the registry when empty")
+	host := flags.String("host", "", "instance address as host:port")
+	zone := flags.String("zone", "", "zone the instance runs in")
+	weight := flags.Int("weight", -1, "traffic weight, the registry default when negative")
+	metadata := metadataFlag{}
+	flags.Var(metadata, "meta", "metadata as key=value, may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *serviceID == "" || *host == "" {
+		return errors.New("usage: register -service ID -host H:P [-id ID] [-weight W] [-meta k=v]")
+	}
+
+	regReq := registry.RegisterInstanceRequest{
+		InstanceID: *instanceID,
+		Host:       *host,
+		Zone:       *zone,
+		Metadata:   metadata,
+	}
+	if *weight >= 0 {
+		regReq.Weight = weight
+	}
+
+	hostStatus, err := app.client.Register(ctx, *serviceID, regReq)
+	if err != nil {
+		return err
+	}
+	return app.printer.instances([]registry.HostStatus{hostStatus})
+}
+
+func deregister(ctx context.Context, app app, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: deregister <serviceID> <instanceID>")
+	}
+	return app.client.Deregister(ctx, args[0], args[1])
+}
+
+func drain(ctx context.Context, app app, args []string) error {
+	flags := flag.NewFlagSet("drain", flag.ContinueOnError)
+	period := flags.Duration("period", 0, "drain period, the registry default when zero")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("usage: drain [-period D] <serviceID> <instanceID>")
+	}
+
+	hostStatus, err := app.client.Drain(ctx, flags.Arg(0), flags.Arg(1), *period)
+	if err != nil {
+		return err
+	}
+	return app.printer.instances([]registry.HostStatus{hostStatus})
+}
+
+func watch(ctx context.Context, app app, args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: watch [serviceID]")
+	}
+	serviceID := ""
+	if len(args) == 1 {
+		serviceID = args[0]
+	}
+	return app.streamClient.Watch(ctx, serviceID, app.printer.event)
+}
+
+func graph(ctx context.Context, app app, args []string) error {
+	flags := flag.NewFlagSet("graph", flag.ContinueOnError)
+	dot := flags.Bool("dot", false, "print the graph in Graphviz DOT format")
+	window := flags.Duration("window", 0, "only edges seen within this window, all when zero")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *dot {
+		graphDOT, err := app.client.GraphDOT(ctx, *window)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = graphDOT.Close() }()
+		_, err = io.Copy(app.printer.writer, graphDOT)
+		return err
+	}
+
+	graphResp, err := app.client.Graph(ctx, *window)
+	if err != nil {
+		return err
+	}
+	return app.printer.graph(graphResp)
+}
+
+func consumers(ctx context.Context, app app, args []string) error {
+	flags := flag.NewFlagSet("consumers", flag.ContinueOnError)
+	window := flags.Duration("window", 0, "only consumers seen within this window, all when zero")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: consumers [-window D] <serviceID>")
+	}
+
+	edges, err := app.client.Consumers(ctx, flags.Arg(0), *window)
+	if err != nil {
+		return err
+	}
+	return app.printer.edges(edges)
+}
+
+func kvGet(ctx context.Context, app app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: kv get <key>")
+	}
+	entry, err := app.client.KVGet(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return app.printer.kvEntry(entry)
+}
+
+func kvPut(ctx context.Context, app app, args []string) error {
+	flags := flag.NewFlagSet("kv put", flag.ContinueOnError)
+	cas := flags.Int64("cas", -1, "only write when the key's modify index equals this, 0 only creates the key")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New("usage: kv put [-cas N] <key> <value|->")
+	}
+
+	value := flags.Arg(1)
+	if value == "-" {
+		in, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		value = string(in)
+	}
+
+	entry, err := app.client.KVPut(ctx, flags.Arg(0), value, casFlag(*cas))
+	if err != nil {
+		return err
+	}
+	return app.printer.kvEntries([]registry.KVEntry{entry})
+}
+
+func kvDel(ctx context.Context, app app, args []string) error {
+	flags := flag.NewFlagSet("kv del", flag.ContinueOnError)
+	recurse := flags.Bool("recurse", false, "delete every key under the prefix")
+	cas := flags.Int64("cas", -1, "only delete when the key's modify index equals this")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: kv del [-recurse] [-cas N] <key>")
+	}
+	return app.client.KVDelete(ctx, flags.Arg(0), *recurse, casFlag(*cas))
+}
+
+func kvLs(ctx context.Context, app app, args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: kv ls [prefix]")
+	}
+	prefix := ""
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+
+	kvResp, err := app.client.KVList(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	return app.printer.kvEntries(kvResp.Entries)
+}
+
+func kvWatch(ctx context.Context, app app, args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: kv watch [prefix]")
+	}
+	prefix := ""
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+
+	index := uint64(0)
+	for {
+		kvResp, err := app.streamClient.KVWatch(ctx, prefix, index, 0)
+		if err != nil {
+			return err
+		}
+		if kvResp.Index != index {
+			if err = app.printer.kvEntries(kvResp.Entries); err != nil {
+				return err
+			}
+		}
+		index = kvResp.Index
+	}
+}
+
+func lockLs(ctx context.Context, app app, _ []string) error {
+	locks, err := app.client.Locks(ctx)
+	if err != nil {
+		return err
+	}
+	return app.printer.locks(locks)
+}
+
+func lockGet(ctx context.Context, app app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: lock get <name>")
+	}
+	lock, err := app.client.Lock(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return app.printer.locks([]registry.Lock{lock})
+}
+
+func lockAcquire(ctx context.Context, app app, args []string) error {
+	flags := flag.NewFlagSet("lock acquire", flag.ContinueOnError)
+	wait := flags.Duration("wait", 0, "how long to wait for the current holder to release the lock")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 3 {
+		return errors.New("usage: lock acquire [-wait D] <name> <serviceID> <instanceID>")
+	}
+
+	lockReq := registry.LockRequest{ServiceID: flags.Arg(1), InstanceID: flags.Arg(2)}
+	lock, err := app.streamClient.AcquireLock(ctx, flags.Arg(0), lockReq, *wait)
+	if err != nil {
+		return err
+	}
+	return app.printer.locks([]registry.Lock{lock})
+}
+
+func lockRelease(ctx context.Context, app app, args []string) error {
+	if len(args) != 3 {
+		return errors.New("usage: lock release <name> <serviceID> <instanceID>")
+	}
+	return app.client.ReleaseLock(ctx, args[0], registry.LockRequest{ServiceID: args[1], InstanceID: args[2]})
+}
+
+func casFlag(cas int64) *uint64 {
+	if cas < 0 {
+		return nil
+	}
+	index := uint64(cas)
+	return &index
+}
+
+func healthCheck(ctx context.Context, app app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: health check <host>")
+	}
+	result, err := health.Probe(ctx, app.httpClient, args[0])
+	if err != nil {
+		return err
+	}
+	return app.printer.probe(args[0], result)
+}
+
+func snapshotExport(ctx context.Context, app app, args []string) error {
+	flags := flag.NewFlagSet("snapshot export", flag.ContinueOnError)
+	file := flags.String("f", "", "output file, stdout when empty")
+	gzipped := flags.Bool("gzip", false, "gzip the snapshot")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	snapshot, err := app.streamClient.Snapshot(ctx, *gzipped)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = snapshot.Close() }()
+
+	writer, closeWriter, err := openOutput(*file)
+	if err != nil {
+		return err
+	}
+	defer closeWriter()
+
+	_, err = io.Copy(writer, snapshot)
+	return err
+}
+
+func snapshotImport(ctx context.Context, app app, args []string) error {
+	flags := flag.NewFlagSet("snapshot import", flag.ContinueOnError)
+	file := flags.String("f", "", "input file, stdin when empty")
+	mode := flags.String("mode", string(registry.Merge), "restore mode: merge or replace")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	reader, closeReader, err := openInput(*file)
+	if err != nil {
+		return err
+	}
+	defer closeReader()
+
+	restoreResp, err := app.streamClient.Restore(ctx, reader, registry.RestoreMode(*mode))
+	if err != nil {
+		return err
+	}
+	return app.printer.restore(restoreResp)
+}
+
+type metadataFlag map[string]string
+
+func (m metadataFlag) String() string {
+	pairs := make([]string, 0, len(m))
+	for key, value := range m {
+		pairs = append(pairs, key+"="+value)
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m metadataFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("metadata %q is not key=value", value)
+	}
+	m[key] = val
+	return nil
+}
+
+func openOutput(file string) (io.Writer, func(), error) {
+	if file == "" {
+		return os.Stdout, func() {}, nil
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { _ = f.Close() }, nil
+}
+
+func openInput(file string) (io.Reader, func(), error) {
+	if file == "" {
+		return os.Stdin, func() {}, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { _ = f.Close() }, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/mat-sik/eureka-go/internal/client"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Lookup(t *testing.T) {
+	// given
+	cases := []struct {
+		args     []string
+		wantArgs []string
+		wantErr  bool
+	}{
+		{args: []string{"services", "ls"}, wantArgs: []string{}},
+		{args: []string{"instances", "ls", "orders"}, wantArgs: []string{"orders"}},
+		{args: []string{"deregister", "orders", "a"}, wantArgs: []string{"orders", "a"}},
+		{args: []string{"services"}, wantErr: true},
+		{args: []string{"unknown"}, wantErr: true},
+		{args: nil, wantErr: true},
+	}
+
+	for _, tc := range cases {
+		// when
+		cmd, args, err := lookup(tc.args)
+
+		// then
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("lookup(%q): got no error", tc.args)
+			}
+			continue
+		}
+		if err != nil || cmd == nil {
+			t.Fatalf("lookup(%q): got %v", tc.args, err)
+		}
+		if strings.Join(args, " ") != strings.Join(tc.wantArgs, " ") {
+			t.Fatalf("lookup(%q) args: got %q, want %q", tc.args, args, tc.wantArgs)
+		}
+	}
+}
+
+func Test_Commands_OutputFormats(t *testing.T) {
+	// given
+	store := registry.NewStore()
+	server := httptest.NewServer(registry.NewHandler(store))
+	defer server.Close()
+
+	run := func(format string, args ...string) string {
+		out := &bytes.Buffer{}
+		c := client.NewClient(server.URL, http.DefaultClient)
+		app := app{client: c, streamClient: c, httpClient: http.DefaultClient, printer: newPrinter(out, format)}
+
+		cmd, cmdArgs, err := lookup(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = cmd(context.Background(), app, cmdArgs); err != nil {
+			t.Fatalf("%q: %v", args, err)
+		}
+		return out.String()
+	}
+
+	// when
+	registered := run(outputTable, "register", "-service", "orders", "-id", "a", "-host", "10.0.0.1:8080", "-meta", "version=1")
+	servicesTable := run(outputTable, "services", "ls")
+	instancesJSON := run(outputJSON, "instances", "ls", "orders")
+	run(outputTable, "kv", "put", "config/timeout", "5s")
+	kvValue := run(outputTable, "kv", "get", "config/timeout")
+	kvTable := run(outputTable, "kv", "ls", "config/")
+	run(outputTable, "deregister", "orders", "a")
+	afterDeregister := run(outputJSON, "services", "ls")
+
+	// then
+	if !strings.HasPrefix(registered, "INSTANCE") || !strings.Contains(registered, "10.0.0.1:8080") {
+		t.Fatalf("register: got %q", registered)
+	}
+	if lines := strings.Split(strings.TrimSpace(servicesTable), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], "orders") {
+		t.Fatalf("services table: got %q", servicesTable)
+	}
+
+	var instancesResp registry.GetHostStatusesResponse
+	if err := json.Unmarshal([]byte(instancesJSON), &instancesResp); err != nil {
+		t.Fatal(err)
+	}
+	if len(instancesResp.HostStatuses) != 1 || instancesResp.HostStatuses[0].Metadata["version"] != "1" {
+		t.Fatalf("instances json: got %+v", instancesResp)
+	}
+
+	if kvValue != "5s\n" {
+		t.Fatalf("kv get: got %q, want the raw value", kvValue)
+	}
+	if !strings.Contains(kvTable, "config/timeout") || !strings.HasPrefix(kvTable, "KEY") {
+		t.Fatalf("kv ls: got %q", kvTable)
+	}
+
+	var servicesResp registry.GetServicesResponse
+	if err := json.Unmarshal([]byte(afterDeregister), &servicesResp); err != nil {
+		t.Fatal(err)
+	}
+	if len(servicesResp.Services) != 0 {
+		t.Fatalf("services after deregister: got %+v", servicesResp.Services)
+	}
+}
+
+func Test_Run_RejectsUnknownOutput(t *testing.T) {
+	// when
+	err := run(context.Background(), []string{"-o", "yaml", "services", "ls"})
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), "unknown output format") {
+		t.Fatalf("got %v, want an unknown output format error", err)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/mat-sik/eureka-go/internal/client"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "eurekactl:", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("eurekactl", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	addr := flags.String("addr", envOr("EUREKA_ADDR", "http://localhost:8080"), "registry address (env EUREKA_ADDR)")
+	output := flags.String("o", envOr("EUREKACTL_OUTPUT", outputTable), "output format: table or json (env EUREKACTL_OUTPUT)")
+	timeout := flags.Duration("timeout", 10*time.Second, "timeout of a single registry request")
+	namespace := flags.String("n", os.Getenv("EUREKA_NAMESPACE"), "registry namespace, the default namespace when empty (env EUREKA_NAMESPACE)")
+	service := flags.String("as", os.Getenv("EUREKA_SERVICE"), "service to report as the caller of lookups (env EUREKA_SERVICE)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *output != outputTable && *output != outputJSON {
+		return fmt.Errorf("unknown output format %q", *output)
+	}
+
+	httpClient := &http.Client{Timeout: *timeout}
+	app := app{
+		client:       client.NewClient(*addr, httpClient).InNamespace(*namespace).AsService(*service),
+		streamClient: client.NewClient(*addr, &http.Client{}).InNamespace(*namespace).AsService(*service),
+		httpClient:   httpClient,
+		printer:      newPrinter(os.Stdout, *output),
+	}
+
+	cmd, cmdArgs, err := lookup(flags.Args())
+	if err != nil {
+		flags.Usage()
+		return err
+	}
+	if err = cmd(ctx, app, cmdArgs); errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+func envOr(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+const usage = `Usage: eurekactl [flags] <command>
+
+Commands:
+  services ls                         list registered services
+  instances ls <serviceID>            list instances of a service
+  register -service ID -host H:P      register an instance
+  deregister <serviceID> <instanceID> deregister an instance
+  drain <serviceID> <instanceID>      drain an instance before it is removed
+  watch [serviceID]                   stream registry changes
+  graph [-dot] [-window D]            show which services look up which others
+  consumers [-window D] <serviceID>   list services that look up a service
+  kv get <key>                        print the value of a key
+  kv put [-cas N] <key> <value|->     write a key, - reads the value from stdin
+  kv del [-recurse] <key>             delete a key or every key under a prefix
+  kv ls [prefix]                      list keys under a prefix
+  kv watch [prefix]                   print keys under a prefix whenever they change
+  lock ls                             list held locks
+  lock get <name>                     show the holder of a lock
+  lock acquire [-wait D] <name> <serviceID> <instanceID>
+                                      take a lock for a registered instance
+  lock release <name> <serviceID> <instanceID>
+                                      give up a lock held by an instance
+  health check <host>                 probe http://<host>/health locally
+  snapshot export [-f file]           dump the registry
+  snapshot import [-f file]           load a dump into the registry
+
+Flags:
+`
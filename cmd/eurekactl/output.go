@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mat-sik/eureka-go/internal/health"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+type printer struct {
+	writer io.Writer
+	format string
+}
+
+func (p printer) services(services map[string][]registry.HostStatus) error {
+	if p.format == outputJSON {
+		return p.json(registry.GetServicesResponse{Services: services})
+	}
+
+	serviceIDs := make([]string, 0, len(services))
+	for serviceID := range services {
+		serviceIDs = append(serviceIDs, serviceID)
+	}
+	slices.Sort(serviceIDs)
+
+	table := p.table("SERVICE", "INSTANCES", "HEALTHY")
+	for _, serviceID := range serviceIDs {
+		healthy := 0
+		for _, hostStatus := range services[serviceID] {
+			if hostStatus.Status == registry.Healthy {
+				healthy++
+			}
+		}
+		table.row(serviceID, len(services[serviceID]), healthy)
+	}
+	return table.flush()
+}
+
+func (p printer) instances(instances []registry.HostStatus) error {
+	if p.format == outputJSON {
+		return p.json(registry.GetHostStatusesResponse{HostStatuses: instances})
+	}
+
+	table := p.table("INSTANCE", "HOST", "ZONE", "STATUS", "HEALTH", "WEIGHT", "LAST CHECK", "ERROR")
+	for _, hostStatus := range instances {
+		lastCheck, checkErr := "never", ""
+		if hostStatus.LastCheck != nil {
+			lastCheck = hostStatus.LastCheck.CheckedAt.Format(time.RFC3339)
+			checkErr = hostStatus.LastCheck.Error
+		}
+		table.row(hostStatus.InstanceID, hostStatus.Host, hostStatus.Zone, hostStatus.Status, hostStatus.Health, hostStatus.Weight, lastCheck, checkErr)
+	}
+	return table.flush()
+}
+
+func (p printer) graph(graphResp registry.GraphResponse) error {
+	if p.format == outputJSON {
+		return p.json(graphResp)
+	}
+	return p.edges(graphResp.Edges)
+}
+
+func (p printer) edges(edges []registry.Edge) error {
+	if p.format == outputJSON {
+		return p.json(edges)
+	}
+
+	table := p.table("CONSUMER", "PROVIDER", "REQUESTS", "LAST SEEN")
+	for _, edge := range edges {
+		table.row(edge.Consumer, edge.Provider, edge.Requests, edge.LastSeen.Format(time.RFC3339))
+	}
+	return table.flush()
+}
+
+func (p printer) kvEntry(entry registry.KVEntry) error {
+	if p.format == outputJSON {
+		return p.json(entry)
+	}
+	_, err := fmt.Fprintln(p.writer, entry.Value)
+	return err
+}
+
+func (p printer) kvEntries(entries []registry.KVEntry) error {
+	if p.format == outputJSON {
+		return p.json(entries)
+	}
+
+	table := p.table("KEY", "MODIFY INDEX", "VALUE")
+	for _, entry := range entries {
+		table.row(entry.Key, entry.ModifyIndex, entry.Value)
+	}
+	return table.flush()
+}
+
+func (p printer) locks(locks []registry.Lock) error {
+	if p.format == outputJSON {
+		return p.json(locks)
+	}
+
+	table := p.table("NAME", "SERVICE", "INSTANCE", "TOKEN", "ACQUIRED")
+	for _, lock := range locks {
+		table.row(lock.Name, lock.ServiceID, lock.InstanceID, lock.Token, lock.AcquiredAt.Format(time.RFC3339))
+	}
+	return table.flush()
+}
+
+func (p printer) event(event registry.Event) error {
+	if p.format == outputJSON {
+		return p.json(event)
+	}
+	_, err := fmt.Fprintf(p.writer, "%s\t%s\t%s\t%s\t%s\n",
+		event.Time.Format(time.RFC3339),
+		event.Type,
+		event.ServiceID,
+		event.Instance.InstanceID,
+		event.Instance.Status,
+	)
+	return err
+}
+
+func (p printer) probe(host string, result health.Result) error {
+	if p.format == outputJSON {
+		check := &registry.Check{Status: result.Status, CheckedAt: time.Now(), Components: result.Components}
+		return p.json(registry.HostStatus{Host: host, Status: result.Status, Health: result.Status, LastCheck: check})
+	}
+	table := p.table("HOST", "COMPONENT", "STATUS")
+	table.row(host, "-", result.Status)
+	for _, name := range slices.Sorted(maps.Keys(result.Components)) {
+		table.row(host, name, result.Components[name].Status)
+	}
+	return table.flush()
+}
+
+func (p printer) restore(restoreResp registry.RestoreResponse) error {
+	if p.format == outputJSON {
+		return p.json(restoreResp)
+	}
+	table := p.table("SERVICES", "INSTANCES", "KEYS")
+	table.row(restoreResp.Services, restoreResp.Instances, restoreResp.Keys)
+	return table.flush()
+}
+
+func (p printer) json(v any) error {
+	return json.NewEncoder(p.writer).Encode(v)
+}
+
+func (p printer) table(columns ...any) table {
+	t := table{writer: tabwriter.NewWriter(p.writer, 0, 0, 2, ' ', 0)}
+	t.row(columns...)
+	return t
+}
+
+type table struct {
+	writer *tabwriter.Writer
+}
+
+func (t table) row(values ...any) {
+	for i, value := range values {
+		if i > 0 {
+			_, _ = fmt.Fprint(t.writer, "\t")
+		}
+		_, _ = fmt.Fprint(t.writer, value)
+	}
+	_, _ = fmt.Fprintln(t.writer)
+}
+
+func (t table) flush() error {
+	return t.writer.Flush()
+}
+
+func newPrinter(writer io.Writer, format string) printer {
+	return printer{
+		writer: writer,
+		format: format,
+	}
+}
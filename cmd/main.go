@@ -1,19 +1,272 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"flag"
+	"github.com/mat-sik/eureka-go/internal/agent"
+	"github.com/mat-sik/eureka-go/internal/federation"
+	"github.com/mat-sik/eureka-go/internal/health"
 	"github.com/mat-sik/eureka-go/internal/props"
 	"github.com/mat-sik/eureka-go/internal/registry"
 	"github.com/mat-sik/eureka-go/internal/server"
+	"github.com/mat-sik/eureka-go/internal/ui"
+	"github.com/mat-sik/eureka-go/internal/webhook"
+	"log"
 	"log/slog"
+	"maps"
 	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 )
 
+var version = "dev"
+
 func main() {
-	store := registry.NewStore()
-	handler := registry.NewHandler(store)
-	s := server.NewServer(props.NewServerProperties(), handler)
+	if len(os.Args) > 1 && os.Args[1] == "agent" {
+		runAgent(os.Args[2:])
+		return
+	}
+	runServer(os.Args[1:])
+}
+
+func runAgent(args []string) {
+	flags := flag.NewFlagSet("agent", flag.ExitOnError)
+	config := flags.String("config", "services.json", "path to the agent service definition file, YAML or JSON")
+	_ = flags.Parse(args)
+
+	definition, err := agent.LoadDefinition(*config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err = agent.NewAgent(definition).Run(ctx); !errors.Is(err, context.Canceled) {
+		slog.Error(err.Error())
+	}
+}
+
+func runServer(args []string) {
+	ctx := context.Background()
+	startedAt := time.Now()
+
+	loader, err := props.NewLoader("eureka", args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config, err := loader.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	storeOpts := []registry.StoreOption{
+		registry.WithAuditLogSize(config.Audit.LogSize),
+		registry.WithChangeLogSize(config.Storage.ChangeLogSize),
+	}
+	if config.Audit.File != "" {
+		auditFile, err := registry.NewRotatingFile(config.Audit.File, config.Audit.FileMaxSize, config.Audit.FileMaxBackups)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer func() { _ = auditFile.Close() }()
+		storeOpts = append(storeOpts, registry.WithAuditWriter(auditFile))
+	}
+
+	root := startNamespace(ctx, config, append(slices.Clip(storeOpts), registry.WithStoreLimits(registry.StoreLimits{
+		MaxServices:            config.Storage.MaxServices,
+		MaxInstancesPerService: config.Storage.MaxInstancesPerService,
+	})), config.Limits, config.Security)
+	var readiness []health.Readiness
+	if len(config.Federation.Peers) > 0 {
+		fed := federation.NewFederation(config.Federation.Peers, &http.Client{Timeout: config.Federation.Timeout}, config.Federation.SyncInterval)
+		go func() {
+			if err := fed.Run(ctx); err != nil {
+				slog.Error(err.Error())
+			}
+		}()
+		root.handlerOpts = append(root.handlerOpts, registry.WithRemoteCatalog(fed), registry.WithRemoteKV(fed))
+		readiness = append(readiness, fed)
+	}
+
+	namespaces := map[string]namespace{registry.DefaultNamespace: root}
+	for name, nsConfig := range config.Namespaces {
+		namespaces[name] = startNamespace(ctx, config, append(slices.Clip(storeOpts), registry.WithNamespace(name), registry.WithStoreLimits(registry.StoreLimits{
+			MaxServices:            nsConfig.MaxServices,
+			MaxInstancesPerService: nsConfig.MaxInstancesPerService,
+		})), nsConfig.Limits, nsConfig.Security)
+	}
+
+	stores := make(registry.Namespaces, len(namespaces))
+	selfNamespaces := make(map[string]health.Namespace, len(namespaces))
+	for _, name := range slices.Sorted(maps.Keys(namespaces)) {
+		ns := namespaces[name]
+		readiness = append(readiness, ns.store)
+		stores[name] = ns.store
+		selfNamespaces[name] = health.Namespace{Store: ns.store, Checker: ns.checker}
+	}
+	defer func() {
+		for _, store := range stores {
+			store.Close()
+		}
+	}()
+
+	webhooks := webhook.NewManager(stores, &http.Client{Timeout: config.Webhook.Timeout}, webhook.Policy{
+		MaxAttempts:    config.Webhook.MaxAttempts,
+		InitialBackoff: config.Webhook.InitialBackoff,
+		MaxBackoff:     config.Webhook.MaxBackoff,
+		QueueSize:      config.Webhook.QueueSize,
+	}, config.Webhook.DeadLetterSize)
+	go func() {
+		if err := webhooks.Run(ctx); err != nil {
+			slog.Error(err.Error())
+		}
+	}()
+
+	watcher := props.NewWatcher(loader, config, configPollInterval)
+	watcher.OnReload(func(config props.Config) {
+		for name, ns := range namespaces {
+			ns.checker.Reconfigure(config.Checker.Interval, config.Checker.Timeout, config.Checker.ReportTTL)
+			limits, security := config.Limits, config.Security
+			if name != registry.DefaultNamespace {
+				limits, security = config.Namespaces[name].Limits, config.Namespaces[name].Security
+			}
+			ns.rateLimiter.Update(newRateLimits(limits))
+			ns.access.Update(newACL(security))
+		}
+	})
+	go func() {
+		if err := watcher.Run(ctx); err != nil {
+			slog.Error(err.Error())
+		}
+	}()
+
+	namespaceHandlers := make(map[string]http.Handler, len(namespaces))
+	for name, ns := range namespaces {
+		namespaceHandlers[name] = registry.NewHandler(ns.store, ns.handlerOpts...)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", namespaceHandlers[registry.DefaultNamespace])
+	mux.Handle("/ns/{namespace}/", registry.NewNamespaceHandler(namespaceHandlers))
+	webhookHandler := root.access.Admin(webhook.NewHandler(webhooks))
+	mux.Handle("/admin/webhooks", webhookHandler)
+	mux.Handle("/admin/webhooks/", webhookHandler)
+	mux.Handle("/ui/", ui.NewHandler())
+	selfHandler := health.NewHandler(selfNamespaces, version, startedAt, readiness...)
+	mux.Handle("/health", selfHandler)
+	mux.Handle("/ready", selfHandler)
+	mux.Handle("/admin/status", root.access.Admin(selfHandler))
+
+	s := server.NewServer(config.Server, mux)
 	if err := s.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
 		slog.Error(err.Error())
 	}
 }
+
+const configPollInterval = 5 * time.Second
+
+type namespace struct {
+	store       *registry.Store
+	checker     health.Checker
+	rateLimiter *registry.RateLimiter
+	access      *registry.AccessControl
+	handlerOpts []registry.Option
+}
+
+// startNamespace runs the background jobs of one namespace, each namespace has
+// its own store, quotas and access rules.
+func startNamespace(ctx context.Context, config props.Config, storeOpts []registry.StoreOption, limits props.LimitProperties, security props.SecurityProperties) namespace {
+	store := registry.NewStore(storeOpts...)
+
+	checker := health.NewChecker(&http.Client{}, store, config.Checker.Interval, config.Checker.Timeout, config.Checker.ReportTTL)
+	go func() {
+		if err := checker.Run(ctx); err != nil {
+			slog.Error(err.Error())
+		}
+	}()
+
+	cache := registry.NewResponseCache(store)
+	if config.Cache.ReadOnly {
+		go func() {
+			if err := cache.RunReadOnly(ctx, config.Cache.ReadOnlyRefresh); err != nil {
+				slog.Error(err.Error())
+			}
+		}()
+	}
+
+	detector := registry.NewOutlierDetector(store, registry.OutlierPolicy{
+		ConsecutiveFailures: config.Outlier.ConsecutiveFailures,
+		Interval:            config.Outlier.Interval,
+		BaseEjectionTime:    config.Outlier.BaseEjectionTime,
+		MaxEjectionTime:     config.Outlier.MaxEjectionTime,
+		MaxEjectionPercent:  config.Outlier.MaxEjectionPercent,
+		MinRequestVolume:    config.Outlier.MinRequestVolume,
+		MinHosts:            config.Outlier.MinHosts,
+		StdevFactor:         config.Outlier.StdevFactor,
+	})
+	go func() {
+		if err := detector.Run(ctx); err != nil {
+			slog.Error(err.Error())
+		}
+	}()
+
+	drainer := registry.NewDrainer(store, config.Drain.Period, config.Drain.SweepInterval)
+	go func() {
+		if err := drainer.Run(ctx); err != nil {
+			slog.Error(err.Error())
+		}
+	}()
+
+	overrideSweeper := registry.NewOverrideSweeper(store, config.Override.SweepInterval)
+	go func() {
+		if err := overrideSweeper.Run(ctx); err != nil {
+			slog.Error(err.Error())
+		}
+	}()
+
+	rateLimiter := registry.NewRateLimiter(newRateLimits(limits))
+	access := registry.NewAccessControl(newACL(security))
+	return namespace{
+		store:       store,
+		checker:     checker,
+		rateLimiter: rateLimiter,
+		access:      access,
+		handlerOpts: []registry.Option{
+			registry.WithResponseCache(cache),
+			registry.WithRegion(config.Region.Region),
+			registry.WithRateLimiter(rateLimiter),
+			registry.WithAccessControl(access),
+			registry.WithOutlierDetector(detector),
+			registry.WithDrainer(drainer),
+		},
+	}
+}
+
+func newRateLimits(limits props.LimitProperties) registry.RateLimits {
+	return registry.RateLimits{
+		MutationsPerClient:  registry.Rate{PerSecond: limits.MutationsPerClientRate, Burst: limits.MutationsPerClientBurst},
+		MutationsPerService: registry.Rate{PerSecond: limits.MutationsPerServiceRate, Burst: limits.MutationsPerServiceBurst},
+		ReadsPerClient:      registry.Rate{PerSecond: limits.ReadsPerClientRate, Burst: limits.ReadsPerClientBurst},
+	}
+}
+
+// newACL expects CIDRs that already passed props validation.
+func newACL(security props.SecurityProperties) registry.ACL {
+	parse := func(cidrs []string) []netip.Prefix {
+		prefixes := make([]netip.Prefix, 0, len(cidrs))
+		for _, cidr := range cidrs {
+			prefixes = append(prefixes, netip.MustParsePrefix(cidr))
+		}
+		return prefixes
+	}
+	return registry.ACL{
+		AdminCIDRs:    parse(security.AdminCIDRs),
+		MutationCIDRs: parse(security.MutationCIDRs),
+	}
+}
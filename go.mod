@@ -2,4 +2,8 @@ module github.com/mat-sik/eureka-go
 
 go 1.24.0
 
-require github.com/sethvargo/go-envconfig v1.1.1
+require (
+	github.com/BurntSushi/toml v1.5.0
+	github.com/sethvargo/go-envconfig v1.1.1
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"github.com/mat-sik/eureka-go/internal/client"
+	"github.com/mat-sik/eureka-go/internal/health"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type Agent struct {
+	client      client.Client
+	probeClient *http.Client
+	definition  Definition
+}
+
+func (a Agent) Run(ctx context.Context) error {
+	for _, service := range a.definition.Services {
+		if err := a.register(ctx, service); err != nil {
+			slog.Warn("failed to register service", "serviceID", service.ServiceID, "instanceID", service.InstanceID, "err", err)
+		}
+	}
+	defer a.deregisterAll(ctx)
+
+	ticker := time.NewTicker(time.Duration(a.definition.CheckInterval))
+	defer ticker.Stop()
+
+	a.checkAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			a.checkAll(ctx)
+		}
+	}
+}
+
+func (a Agent) checkAll(ctx context.Context) {
+	for _, service := range a.definition.Services {
+		check := a.check(ctx, service)
+		if ctx.Err() != nil {
+			return
+		}
+		if err := a.push(ctx, service, check); err != nil {
+			slog.Warn("failed to push health", "serviceID", service.ServiceID, "instanceID", service.InstanceID, "err", err)
+		}
+	}
+}
+
+func (a Agent) check(ctx context.Context, service Service) registry.Check {
+	result, err := health.ProbeURL(ctx, a.probeClient, service.healthURL())
+	check := registry.Check{
+		Status:     result.Status,
+		CheckedAt:  time.Now(),
+		Components: result.Components,
+	}
+	if err != nil {
+		check.Status = registry.Down
+		check.Error = err.Error()
+	}
+	slog.Info("agent check finished", "serviceID", service.ServiceID, "instanceID", service.InstanceID, "status", check.Status)
+	return check
+}
+
+func (a Agent) push(ctx context.Context, service Service, check registry.Check) error {
+	err := a.client.PutHealth(ctx, service.ServiceID, service.InstanceID, check)
+	var statusErr *client.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		return err
+	}
+
+	slog.Info("instance missing from registry, registering again", "serviceID", service.ServiceID, "instanceID", service.InstanceID)
+	if err = a.register(ctx, service); err != nil {
+		return err
+	}
+	return a.client.PutHealth(ctx, service.ServiceID, service.InstanceID, check)
+}
+
+func (a Agent) register(ctx context.Context, service Service) error {
+	_, err := a.client.Register(ctx, service.ServiceID, registry.RegisterInstanceRequest{
+		InstanceID:    service.InstanceID,
+		Host:          service.address(),
+		Zone:          a.definition.Zone,
+		Metadata:      service.Metadata,
+		Weight:        service.Weight,
+		ReportsHealth: true,
+	})
+	return err
+}
+
+func (a Agent) deregisterAll(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deregisterTimeout)
+	defer cancel()
+
+	for _, service := range a.definition.Services {
+		if a.definition.DrainPeriod > 0 {
+			a.drain(ctx, service)
+			continue
+		}
+		if err := a.client.Deregister(ctx, service.ServiceID, service.InstanceID); err != nil {
+			slog.Warn("failed to deregister service", "serviceID", service.ServiceID, "instanceID", service.InstanceID, "err", err)
+			continue
+		}
+		slog.Info("deregistered service", "serviceID", service.ServiceID, "instanceID", service.InstanceID)
+	}
+}
+
+// drain leaves the removal to the registry once the drain period is over, so
+// callers stop picking the instance while it finishes in-flight requests.
+func (a Agent) drain(ctx context.Context, service Service) {
+	if _, err := a.client.Drain(ctx, service.ServiceID, service.InstanceID, time.Duration(a.definition.DrainPeriod)); err != nil {
+		slog.Warn("failed to drain service", "serviceID", service.ServiceID, "instanceID", service.InstanceID, "err", err)
+		return
+	}
+	slog.Info("draining service", "serviceID", service.ServiceID, "instanceID", service.InstanceID, "period", time.Duration(a.definition.DrainPeriod))
+}
+
+const deregisterTimeout = 5 * time.Second
+
+func NewAgent(definition Definition) Agent {
+	return Agent{
+		client:      client.NewClient(definition.Registry, &http.Client{Timeout: time.Duration(definition.CheckTimeout)}).InNamespace(definition.Namespace),
+		probeClient: &http.Client{Timeout: time.Duration(definition.CheckTimeout)},
+		definition:  definition,
+	}
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_LoadDefinition_YAMLAndJSON(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "services.yaml")
+	jsonPath := filepath.Join(dir, "services.json")
+	unknownPath := filepath.Join(dir, "unknown.yml")
+	writeFile(t, yamlPath, `
+registry: http://127.0.0.1:8080
+check_interval: 5s
+services:
+  - service_id: orders
+    host: 10.0.0.1
+    port: 8081
+    metadata:
+      version: "1"
+`)
+	writeFile(t, jsonPath, `{"registry":"http://127.0.0.1:8080","check_interval":"5s","services":[{"service_id":"orders","host":"10.0.0.1","port":8081,"metadata":{"version":"1"}}]}`)
+	writeFile(t, unknownPath, "registry: http://127.0.0.1:8080\nretries: 3\n")
+
+	// when
+	fromYAML, yamlErr := LoadDefinition(yamlPath)
+	fromJSON, jsonErr := LoadDefinition(jsonPath)
+	_, unknownErr := LoadDefinition(unknownPath)
+
+	// then
+	if yamlErr != nil || jsonErr != nil {
+		t.Fatalf("errors: got %v, %v", yamlErr, jsonErr)
+	}
+	if fromYAML.CheckInterval != Duration(5*time.Second) || fromYAML.CheckTimeout != Duration(2*time.Second) {
+		t.Fatalf("durations: got %v, %v", fromYAML.CheckInterval, fromYAML.CheckTimeout)
+	}
+	service := fromYAML.Services[0]
+	if service.InstanceID != "10.0.0.1:8081" || service.HealthPath != "/health" || service.Metadata["version"] != "1" {
+		t.Fatalf("service: got %+v", service)
+	}
+	if fromJSON.Services[0].InstanceID != service.InstanceID || fromJSON.CheckInterval != fromYAML.CheckInterval {
+		t.Fatalf("json and yaml differ: got %+v and %+v", fromJSON, fromYAML)
+	}
+	if unknownErr == nil {
+		t.Fatal("unknown field: got no error")
+	}
+}
+
+func Test_Agent_FailingProbeMarksDown(t *testing.T) {
+	// given
+	store := registry.NewStore()
+	server := httptest.NewServer(registry.NewHandler(store))
+	defer server.Close()
+
+	unreachable := httptest.NewServer(nil)
+	_, port, err := net.SplitHostPort(unreachable.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	unreachable.Close()
+	portNumber, _ := strconv.Atoi(port)
+
+	definition := Definition{
+		Registry:     server.URL,
+		CheckTimeout: Duration(time.Second),
+		Services:     []Service{{ServiceID: "orders", InstanceID: "a", Host: "127.0.0.1", Port: portNumber, HealthPath: "/health"}},
+	}
+	agent := NewAgent(definition)
+	if err = agent.register(context.Background(), definition.Services[0]); err != nil {
+		t.Fatal(err)
+	}
+	store.Put("orders", "a", registry.Healthy)
+
+	// when
+	agent.checkAll(context.Background())
+
+	// then
+	got, ok := store.Find("orders", "a")
+	if !ok {
+		t.Fatal("instance not found")
+	}
+	if got.Status != registry.Down || got.LastCheck == nil || got.LastCheck.Error == "" {
+		t.Fatalf("got %s with check %+v, want down with the probe error", got.Status, got.LastCheck)
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Definition struct {
+	Registry      string    `json:"registry" yaml:"registry"`
+	Namespace     string    `json:"namespace" yaml:"namespace"`
+	Zone          string    `json:"zone" yaml:"zone"`
+	CheckInterval Duration  `json:"check_interval" yaml:"check_interval"`
+	CheckTimeout  Duration  `json:"check_timeout" yaml:"check_timeout"`
+	DrainPeriod   Duration  `json:"drain_period" yaml:"drain_period"`
+	Services      []Service `json:"services" yaml:"services"`
+}
+
+type Service struct {
+	ServiceID  string            `json:"service_id" yaml:"service_id"`
+	InstanceID string            `json:"instance_id" yaml:"instance_id"`
+	Host       string            `json:"host" yaml:"host"`
+	Port       int               `json:"port" yaml:"port"`
+	HealthPath string            `json:"health_path" yaml:"health_path"`
+	Weight     *int              `json:"weight" yaml:"weight"`
+	Metadata   map[string]string `json:"metadata" yaml:"metadata"`
+}
+
+func (s Service) address() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+func (s Service) healthURL() string {
+	return fmt.Sprintf("http://%s%s", net.JoinHostPort("127.0.0.1", strconv.Itoa(s.Port)), s.HealthPath)
+}
+
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// LoadDefinition reads YAML from .yaml and .yml files and JSON from anything
+// else.
+func LoadDefinition(path string) (Definition, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Definition{}, err
+	}
+
+	var definition Definition
+	if err = decodeDefinition(path, content, &definition); err != nil {
+		return Definition{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if err = definition.complete(); err != nil {
+		return Definition{}, fmt.Errorf("validate %s: %w", path, err)
+	}
+	return definition, nil
+}
+
+func decodeDefinition(path string, content []byte, definition *Definition) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		return decoder.Decode(definition)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(definition)
+	}
+}
+
+func (d *Definition) complete() error {
+	if d.Registry == "" {
+		return errors.New("registry address is required")
+	}
+	if d.CheckInterval == 0 {
+		d.CheckInterval = Duration(10 * time.Second)
+	}
+	if d.CheckTimeout == 0 {
+		d.CheckTimeout = Duration(2 * time.Second)
+	}
+	if len(d.Services) == 0 {
+		return errors.New("at least one service is required")
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, 0)
+	for i := range d.Services {
+		service := &d.Services[i]
+		if service.ServiceID == "" {
+			errs = append(errs, fmt.Errorf("services[%d]: service_id is required", i))
+		}
+		if service.Port <= 0 || service.Port > 65535 {
+			errs = append(errs, fmt.Errorf("services[%d]: port %d is out of range", i, service.Port))
+		}
+		if service.Host == "" {
+			service.Host = hostname
+		}
+		if service.HealthPath == "" {
+			service.HealthPath = "/health"
+		}
+		if service.InstanceID == "" {
+			service.InstanceID = service.address()
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,415 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	service    string
+}
+
+// InNamespace returns a client for the routes of namespace, the default
+// namespace keeps the unprefixed routes.
+func (c Client) InNamespace(namespace string) Client {
+	if namespace != "" && namespace != registry.DefaultNamespace {
+		c.baseURL += "/ns/" + url.PathEscape(namespace)
+	}
+	return c
+}
+
+// AsService returns a client that names serviceID as the caller of its
+// lookups, so the registry can track which services depend on which.
+func (c Client) AsService(serviceID string) Client {
+	c.service = serviceID
+	return c
+}
+
+func (c Client) Services(ctx context.Context) (map[string][]registry.HostStatus, error) {
+	var resp registry.GetServicesResponse
+	if err := c.do(ctx, http.MethodGet, "/v2/services", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Services, nil
+}
+
+func (c Client) Apps(ctx context.Context) (registry.AppsResponse, error) {
+	var resp registry.AppsResponse
+	err := c.do(ctx, http.MethodGet, "/apps", nil, &resp)
+	return resp, err
+}
+
+func (c Client) Delta(ctx context.Context, since uint64) (registry.DeltaResponse, error) {
+	var resp registry.DeltaResponse
+	err := c.do(ctx, http.MethodGet, fmt.Sprintf("/apps/delta?since=%d", since), nil, &resp)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusGone {
+		return registry.DeltaResponse{FullRefetch: true}, nil
+	}
+	return resp, err
+}
+
+func (c Client) Instances(ctx context.Context, serviceID string) ([]registry.HostStatus, error) {
+	var resp registry.GetHostStatusesResponse
+	if err := c.do(ctx, http.MethodGet, instancesPath(serviceID), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.HostStatuses, nil
+}
+
+type LookupOptions struct {
+	Region         string
+	Zone           string
+	FallbackRemote bool
+	Strategy       string
+	Count          int
+}
+
+func (c Client) Lookup(ctx context.Context, serviceID string, opts LookupOptions) (registry.GetHostStatusesResponse, error) {
+	query := url.Values{}
+	if opts.Region != "" {
+		query.Set("region", opts.Region)
+	}
+	if opts.Zone != "" {
+		query.Set("zone", opts.Zone)
+	}
+	if opts.FallbackRemote {
+		query.Set("fallback", "remote")
+	}
+	if opts.Strategy != "" {
+		query.Set("strategy", opts.Strategy)
+	}
+	if opts.Count > 0 {
+		query.Set("count", strconv.Itoa(opts.Count))
+	}
+
+	path := instancesPath(serviceID)
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var resp registry.GetHostStatusesResponse
+	err := c.do(ctx, http.MethodGet, path, nil, &resp)
+	return resp, err
+}
+
+func (c Client) Register(ctx context.Context, serviceID string, regReq registry.RegisterInstanceRequest) (registry.HostStatus, error) {
+	var hostStatus registry.HostStatus
+	if regReq.InstanceID == "" {
+		err := c.do(ctx, http.MethodPost, instancesPath(serviceID), regReq, &hostStatus)
+		return hostStatus, err
+	}
+	err := c.do(ctx, http.MethodPut, instancePath(serviceID, regReq.InstanceID), regReq, &hostStatus)
+	return hostStatus, err
+}
+
+func (c Client) Deregister(ctx context.Context, serviceID string, instanceID string) error {
+	return c.do(ctx, http.MethodDelete, instancePath(serviceID, instanceID), nil, nil)
+}
+
+func (c Client) Drain(ctx context.Context, serviceID string, instanceID string, period time.Duration) (registry.HostStatus, error) {
+	var drainReq registry.DrainRequest
+	if period > 0 {
+		drainReq.Period = period.String()
+	}
+	var hostStatus registry.HostStatus
+	err := c.do(ctx, http.MethodPut, instancePath(serviceID, instanceID)+"/drain", drainReq, &hostStatus)
+	return hostStatus, err
+}
+
+func (c Client) PutHealth(ctx context.Context, serviceID string, instanceID string, check registry.Check) error {
+	return c.do(ctx, http.MethodPut, instancePath(serviceID, instanceID)+"/health", check, nil)
+}
+
+func (c Client) ReportCall(ctx context.Context, serviceID string, reportReq registry.ReportCallRequest) error {
+	return c.do(ctx, http.MethodPost, reportsPath(serviceID), reportReq, nil)
+}
+
+func (c Client) Watch(ctx context.Context, serviceID string, onEvent func(registry.Event) error) error {
+	path := "/v2/events"
+	if serviceID != "" {
+		path += "?service=" + url.QueryEscape(serviceID)
+	}
+
+	resp, err := c.send(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event registry.Event
+		if err = json.Unmarshal([]byte(data), &event); err != nil {
+			return err
+		}
+		if err = onEvent(event); err != nil {
+			return err
+		}
+	}
+	if err = scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (c Client) Snapshot(ctx context.Context, gzipped bool) (io.ReadCloser, error) {
+	path := "/admin/snapshot"
+	if gzipped {
+		path += "?format=gzip"
+	}
+
+	resp, err := c.send(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c Client) Restore(ctx context.Context, snapshot io.Reader, mode registry.RestoreMode) (registry.RestoreResponse, error) {
+	path := "/admin/restore?mode=" + url.QueryEscape(string(mode))
+
+	resp, err := c.sendRaw(ctx, http.MethodPost, path, snapshot, "application/json")
+	if err != nil {
+		return registry.RestoreResponse{}, err
+	}
+	defer closeBody(resp)
+
+	var restoreResp registry.RestoreResponse
+	err = json.NewDecoder(resp.Body).Decode(&restoreResp)
+	return restoreResp, err
+}
+
+func (c Client) Graph(ctx context.Context, window time.Duration) (registry.GraphResponse, error) {
+	var resp registry.GraphResponse
+	err := c.do(ctx, http.MethodGet, graphPath("json", window), nil, &resp)
+	return resp, err
+}
+
+func (c Client) GraphDOT(ctx context.Context, window time.Duration) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, http.MethodGet, graphPath("dot", window), nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c Client) Consumers(ctx context.Context, serviceID string, window time.Duration) ([]registry.Edge, error) {
+	path := "/admin/services/" + url.PathEscape(serviceID) + "/consumers"
+	if window > 0 {
+		path += "?window=" + url.QueryEscape(window.String())
+	}
+
+	var resp registry.ConsumersResponse
+	err := c.do(ctx, http.MethodGet, path, nil, &resp)
+	return resp.Consumers, err
+}
+
+func (c Client) KVGet(ctx context.Context, key string) (registry.KVEntry, error) {
+	var entry registry.KVEntry
+	err := c.do(ctx, http.MethodGet, kvPath(key, nil), nil, &entry)
+	return entry, err
+}
+
+func (c Client) KVList(ctx context.Context, prefix string) (registry.KVResponse, error) {
+	return c.KVWatch(ctx, prefix, 0, 0)
+}
+
+// KVWatch lists the keys under prefix once they changed after index, or after
+// wait elapsed. A zero index lists them right away.
+func (c Client) KVWatch(ctx context.Context, prefix string, index uint64, wait time.Duration) (registry.KVResponse, error) {
+	query := url.Values{}
+	query.Set("recurse", "")
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+	}
+	if wait > 0 {
+		query.Set("wait", wait.String())
+	}
+
+	var resp registry.KVResponse
+	err := c.do(ctx, http.MethodGet, kvPath(prefix, query), nil, &resp)
+	return resp, err
+}
+
+// KVPut writes value under key. A non-nil cas only writes when the key's modify
+// index still equals it, zero meaning the key must not exist yet.
+func (c Client) KVPut(ctx context.Context, key string, value string, cas *uint64) (registry.KVEntry, error) {
+	query := url.Values{}
+	if cas != nil {
+		query.Set("cas", strconv.FormatUint(*cas, 10))
+	}
+
+	resp, err := c.sendRaw(ctx, http.MethodPut, kvPath(key, query), strings.NewReader(value), "application/octet-stream")
+	if err != nil {
+		return registry.KVEntry{}, err
+	}
+	defer closeBody(resp)
+
+	var entry registry.KVEntry
+	err = json.NewDecoder(resp.Body).Decode(&entry)
+	return entry, err
+}
+
+func (c Client) KVDelete(ctx context.Context, key string, recurse bool, cas *uint64) error {
+	query := url.Values{}
+	if recurse {
+		query.Set("recurse", "")
+	}
+	if cas != nil {
+		query.Set("cas", strconv.FormatUint(*cas, 10))
+	}
+	return c.do(ctx, http.MethodDelete, kvPath(key, query), nil, nil)
+}
+
+func (c Client) Locks(ctx context.Context) ([]registry.Lock, error) {
+	var resp registry.LocksResponse
+	err := c.do(ctx, http.MethodGet, "/v2/locks", nil, &resp)
+	return resp.Locks, err
+}
+
+func (c Client) Lock(ctx context.Context, name string) (registry.Lock, error) {
+	var lock registry.Lock
+	err := c.do(ctx, http.MethodGet, lockPath(name, 0), nil, &lock)
+	return lock, err
+}
+
+// AcquireLock takes the lock for the instance, waiting up to wait for its
+// current holder to let go. The registry answers with 409 Conflict when the
+// lock is still held after that.
+func (c Client) AcquireLock(ctx context.Context, name string, lockReq registry.LockRequest, wait time.Duration) (registry.Lock, error) {
+	var lock registry.Lock
+	err := c.do(ctx, http.MethodPut, lockPath(name, wait), lockReq, &lock)
+	return lock, err
+}
+
+func (c Client) ReleaseLock(ctx context.Context, name string, lockReq registry.LockRequest) error {
+	return c.do(ctx, http.MethodDelete, lockPath(name, 0), lockReq, nil)
+}
+
+func (c Client) do(ctx context.Context, method string, path string, reqBody any, respBody any) error {
+	resp, err := c.send(ctx, method, path, reqBody)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+
+	if respBody == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(respBody)
+}
+
+func (c Client) send(ctx context.Context, method string, path string, reqBody any) (*http.Response, error) {
+	if reqBody == nil {
+		return c.sendRaw(ctx, method, path, nil, "")
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := json.NewEncoder(buffer).Encode(reqBody); err != nil {
+		return nil, err
+	}
+	return c.sendRaw(ctx, method, path, buffer, "application/json")
+}
+
+func (c Client) sendRaw(ctx context.Context, method string, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.service != "" {
+		req.Header.Set(registry.CallerServiceHeader, c.service)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer closeBody(resp)
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+	}
+
+	return resp, nil
+}
+
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("registry responded with %d: %s", e.StatusCode, e.Message)
+}
+
+func graphPath(format string, window time.Duration) string {
+	query := url.Values{}
+	query.Set("format", format)
+	if window > 0 {
+		query.Set("window", window.String())
+	}
+	return "/admin/graph?" + query.Encode()
+}
+
+func kvPath(key string, query url.Values) string {
+	path := "/v2/kv/" + (&url.URL{Path: key}).EscapedPath()
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path
+}
+
+func lockPath(name string, wait time.Duration) string {
+	path := "/v2/locks/" + url.PathEscape(name)
+	if wait > 0 {
+		path += "?wait=" + url.QueryEscape(wait.String())
+	}
+	return path
+}
+
+func reportsPath(serviceID string) string {
+	return fmt.Sprintf("/v2/services/%s/reports", url.PathEscape(serviceID))
+}
+
+func instancesPath(serviceID string) string {
+	return fmt.Sprintf("/v2/services/%s/instances", url.PathEscape(serviceID))
+}
+
+func instancePath(serviceID string, instanceID string) string {
+	return fmt.Sprintf("%s/%s", instancesPath(serviceID), url.PathEscape(instanceID))
+}
+
+func closeBody(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		slog.Warn("failed to close response body", "err", err)
+	}
+}
+
+func NewClient(baseURL string, httpClient *http.Client) Client {
+	return Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
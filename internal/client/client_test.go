@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Client_InstanceLifecycle(t *testing.T) {
+	// given
+	ctx := context.Background()
+	store := registry.NewStore()
+	server := httptest.NewServer(registry.NewHandler(store))
+	defer server.Close()
+	c := NewClient(server.URL+"/", http.DefaultClient)
+
+	// when
+	created, createErr := c.Register(ctx, "orders", registry.RegisterInstanceRequest{InstanceID: "a", Host: "10.0.0.1:8080", Zone: "eu-1"})
+	generated, generateErr := c.Register(ctx, "orders", registry.RegisterInstanceRequest{Host: "10.0.0.2:8080"})
+	healthErr := c.PutHealth(ctx, "orders", "a", registry.Check{Status: registry.Healthy})
+	instances, instancesErr := c.Instances(ctx, "orders")
+	lookupResp, lookupErr := c.Lookup(ctx, "orders", LookupOptions{Zone: "eu-1"})
+	services, servicesErr := c.Services(ctx)
+	deregisterErr := c.Deregister(ctx, "orders", "a")
+	missingErr := c.Deregister(ctx, "orders", "a")
+
+	// then
+	for _, err := range []error{createErr, generateErr, healthErr, instancesErr, lookupErr, servicesErr, deregisterErr} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if created.InstanceID != "a" || generated.InstanceID == "" {
+		t.Fatalf("registered: got %+v and %+v", created, generated)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("instances: got %+v, want 2", instances)
+	}
+	if lookupResp.HostStatuses[0].InstanceID != "a" || lookupResp.HostStatuses[0].Status != registry.Healthy {
+		t.Fatalf("lookup: got %+v, want the healthy same-zone instance first", lookupResp.HostStatuses)
+	}
+	if len(services["orders"]) != 2 {
+		t.Fatalf("services: got %+v", services)
+	}
+
+	var statusErr *StatusError
+	if !errors.As(missingErr, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("second deregister: got %v, want a 404 status error", missingErr)
+	}
+}
+
+func Test_Client_SnapshotRestore(t *testing.T) {
+	// given
+	ctx := context.Background()
+	source := registry.NewStore()
+	if _, err := source.KV().Put("config/timeout", "5s", nil); err != nil {
+		t.Fatal(err)
+	}
+	sourceServer := httptest.NewServer(registry.NewHandler(source))
+	defer sourceServer.Close()
+	targetServer := httptest.NewServer(registry.NewHandler(registry.NewStore()))
+	defer targetServer.Close()
+
+	sourceClient := NewClient(sourceServer.URL, http.DefaultClient)
+	if _, err := sourceClient.Register(ctx, "orders", registry.RegisterInstanceRequest{InstanceID: "a", Host: "10.0.0.1:8080"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	snapshot, err := sourceClient.Snapshot(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Close()
+	restoreResp, err := NewClient(targetServer.URL, http.DefaultClient).Restore(ctx, snapshot, registry.Replace)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoreResp.Services != 1 || restoreResp.Instances != 1 || restoreResp.Keys != 1 {
+		t.Fatalf("restore: got %+v", restoreResp)
+	}
+}
+
+func Test_Client_KV(t *testing.T) {
+	// given
+	ctx := context.Background()
+	server := httptest.NewServer(registry.NewHandler(registry.NewStore()))
+	defer server.Close()
+	c := NewClient(server.URL, http.DefaultClient)
+	zero := uint64(0)
+
+	// when
+	created, createErr := c.KVPut(ctx, "config/orders/timeout", "5s", &zero)
+	_, conflictErr := c.KVPut(ctx, "config/orders/timeout", "6s", &zero)
+	got, getErr := c.KVGet(ctx, "config/orders/timeout")
+	listed, listErr := c.KVList(ctx, "config/")
+
+	watched := make(chan registry.KVResponse, 1)
+	go func() {
+		kvResp, err := c.KVWatch(ctx, "config/", listed.Index, 5*time.Second)
+		if err != nil {
+			t.Error(err)
+		}
+		watched <- kvResp
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if _, err := c.KVPut(ctx, "config/orders/retries", "3", nil); err != nil {
+		t.Fatal(err)
+	}
+	afterWatch := <-watched
+
+	deleteErr := c.KVDelete(ctx, "config/orders/", true, nil)
+	afterDelete, _ := c.KVList(ctx, "config/")
+
+	// then
+	for _, err := range []error{createErr, getErr, listErr, deleteErr} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var statusErr *StatusError
+	if !errors.As(conflictErr, &statusErr) || statusErr.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("create twice: got %v, want a 412 status error", conflictErr)
+	}
+	if got != created || got.Value != "5s" {
+		t.Fatalf("get: got %+v, want %+v", got, created)
+	}
+	if len(listed.Entries) != 1 || len(afterWatch.Entries) != 2 || afterWatch.Index <= listed.Index {
+		t.Fatalf("watch: got %+v after %+v", afterWatch, listed)
+	}
+	if len(afterDelete.Entries) != 0 {
+		t.Fatalf("after delete: got %+v, want none", afterDelete.Entries)
+	}
+}
+
+func Test_Client_Locks(t *testing.T) {
+	// given
+	ctx := context.Background()
+	server := httptest.NewServer(registry.NewHandler(registry.NewStore()))
+	defer server.Close()
+	c := NewClient(server.URL, http.DefaultClient)
+	for _, instanceID := range []string{"a", "b"} {
+		if _, err := c.Register(ctx, "jobs", registry.RegisterInstanceRequest{InstanceID: instanceID, Host: "10.0.0.1:8080"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	holderA := registry.LockRequest{ServiceID: "jobs", InstanceID: "a"}
+	holderB := registry.LockRequest{ServiceID: "jobs", InstanceID: "b"}
+
+	// when
+	acquired, acquireErr := c.AcquireLock(ctx, "leader", holderA, 0)
+	_, heldErr := c.AcquireLock(ctx, "leader", holderB, 10*time.Millisecond)
+	held, getErr := c.Lock(ctx, "leader")
+	releaseErr := c.ReleaseLock(ctx, "leader", holderA)
+	locks, locksErr := c.Locks(ctx)
+
+	// then
+	for _, err := range []error{acquireErr, getErr, releaseErr, locksErr} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var statusErr *StatusError
+	if !errors.As(heldErr, &statusErr) || statusErr.StatusCode != http.StatusConflict {
+		t.Fatalf("second holder: got %v, want a 409 status error", heldErr)
+	}
+	if held != acquired || held.InstanceID != "a" {
+		t.Fatalf("lock: got %+v, want %+v", held, acquired)
+	}
+	if len(locks) != 0 {
+		t.Fatalf("locks after release: got %+v, want none", locks)
+	}
+}
+
+func Test_Client_NamespaceAndCaller(t *testing.T) {
+	// given
+	var paths []string
+	var callers []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		paths = append(paths, request.URL.Path)
+		callers = append(callers, request.Header.Get(registry.CallerServiceHeader))
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write([]byte(`{"host_statuses":[]}`))
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, http.DefaultClient)
+
+	// when
+	_, defaultErr := c.InNamespace(registry.DefaultNamespace).Instances(context.Background(), "orders")
+	_, teamErr := c.InNamespace("team-a").AsService("checkout").Instances(context.Background(), "orders")
+
+	// then
+	if defaultErr != nil || teamErr != nil {
+		t.Fatalf("errors: got %v, %v", defaultErr, teamErr)
+	}
+	want := "/v2/services/orders/instances,/ns/team-a/v2/services/orders/instances"
+	if got := strings.Join(paths, ","); got != want {
+		t.Fatalf("paths: got %s, want %s", got, want)
+	}
+	if callers[0] != "" || callers[1] != "checkout" {
+		t.Fatalf("callers: got %q", callers)
+	}
+}
@@ -0,0 +1,243 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"github.com/mat-sik/eureka-go/internal/client"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const staleAfterIntervals = 3
+
+type Federation struct {
+	peers    map[string]client.Client
+	catalogs map[string]*catalog
+	interval time.Duration
+}
+
+type catalog struct {
+	services   map[string]map[string]registry.HostStatus
+	version    uint64
+	synced     bool
+	lastSynced time.Time
+	kv         []registry.KVEntry
+	kvSynced   bool
+	lock       sync.RWMutex
+}
+
+func (f *Federation) Run(ctx context.Context) error {
+	f.syncAll(ctx)
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			f.syncAll(ctx)
+		}
+	}
+}
+
+func (f *Federation) syncAll(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for region, peer := range f.peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f.sync(ctx, region, peer); err != nil && ctx.Err() == nil {
+				slog.Warn("failed to sync remote region", "region", region, "err", err)
+			}
+			if err := f.syncKV(ctx, region, peer); err != nil && ctx.Err() == nil {
+				slog.Warn("failed to sync remote region key/value store", "region", region, "err", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (f *Federation) sync(ctx context.Context, region string, peer client.Client) error {
+	c := f.catalogs[region]
+
+	c.lock.RLock()
+	synced, version := c.synced, c.version
+	c.lock.RUnlock()
+
+	if synced {
+		deltaResp, err := peer.Delta(ctx, version)
+		if err != nil {
+			return err
+		}
+		if !deltaResp.FullRefetch {
+			if c.applyDelta(deltaResp) {
+				c.markSynced()
+				return nil
+			}
+			slog.Warn("remote region catalog diverged, refetching", "region", region, "version", deltaResp.Version)
+		} else {
+			slog.Info("remote region requested full refetch", "region", region, "version", version)
+		}
+	}
+
+	appsResp, err := peer.Apps(ctx)
+	if err != nil {
+		return err
+	}
+	c.replace(appsResp)
+	return nil
+}
+
+// syncKV mirrors the whole key/value store of the peer, which is small
+// compared to the catalog and has no delta endpoint.
+func (f *Federation) syncKV(ctx context.Context, region string, peer client.Client) error {
+	kvResp, err := peer.KVList(ctx, "")
+	if err != nil {
+		return err
+	}
+
+	c := f.catalogs[region]
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.kv = kvResp.Entries
+	c.kvSynced = true
+	return nil
+}
+
+func (c *catalog) replace(appsResp registry.AppsResponse) {
+	services := make(map[string]map[string]registry.HostStatus, len(appsResp.Services))
+	for serviceID, hostStatuses := range appsResp.Services {
+		services[serviceID] = make(map[string]registry.HostStatus, len(hostStatuses))
+		for _, hostStatus := range hostStatuses {
+			services[serviceID][hostStatus.InstanceID] = hostStatus
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.services = services
+	c.version = appsResp.Version
+	c.synced = true
+	c.lastSynced = time.Now()
+}
+
+func (c *catalog) markSynced() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.lastSynced = time.Now()
+}
+
+func (c *catalog) applyDelta(deltaResp registry.DeltaResponse) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, event := range deltaResp.Changes {
+		instances, ok := c.services[event.ServiceID]
+		if event.Type == registry.Removed {
+			delete(instances, event.Instance.InstanceID)
+			if len(instances) == 0 {
+				delete(c.services, event.ServiceID)
+			}
+			continue
+		}
+		if !ok {
+			instances = make(map[string]registry.HostStatus)
+			c.services[event.ServiceID] = instances
+		}
+		instances[event.Instance.InstanceID] = event.Instance
+	}
+	c.version = deltaResp.Version
+
+	return registry.ReconcileHash(c.hostStatuses()) == deltaResp.Hash
+}
+
+func (c *catalog) hostStatuses() map[string][]registry.HostStatus {
+	result := make(map[string][]registry.HostStatus, len(c.services))
+	for serviceID, instances := range c.services {
+		result[serviceID] = slices.Collect(maps.Values(instances))
+	}
+	return result
+}
+
+func (f *Federation) Regions() []string {
+	return slices.Sorted(maps.Keys(f.catalogs))
+}
+
+// Ready reports whether every remote catalog has synced within the last few
+// intervals, so that lookups are not served from a stale copy.
+func (f *Federation) Ready() (bool, string) {
+	for _, region := range f.Regions() {
+		c := f.catalogs[region]
+
+		c.lock.RLock()
+		synced, lastSynced := c.synced, c.lastSynced
+		c.lock.RUnlock()
+
+		if !synced || time.Since(lastSynced) > staleAfterIntervals*f.interval {
+			return false, fmt.Sprintf("remote region %s is out of sync", region)
+		}
+	}
+	return true, ""
+}
+
+func (f *Federation) Lookup(region string, serviceID string) ([]registry.HostStatus, bool) {
+	c, ok := f.catalogs[region]
+	if !ok {
+		return nil, false
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if !c.synced {
+		return nil, false
+	}
+	return slices.Collect(maps.Values(c.services[serviceID])), true
+}
+
+func (f *Federation) LookupKV(region string, prefix string) ([]registry.KVEntry, bool) {
+	c, ok := f.catalogs[region]
+	if !ok {
+		return nil, false
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if !c.kvSynced {
+		return nil, false
+	}
+	entries := make([]registry.KVEntry, 0)
+	for _, entry := range c.kv {
+		if strings.HasPrefix(entry.Key, prefix) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, true
+}
+
+func NewFederation(peerURLs map[string]string, httpClient *http.Client, interval time.Duration) *Federation {
+	f := &Federation{
+		peers:    make(map[string]client.Client, len(peerURLs)),
+		catalogs: make(map[string]*catalog, len(peerURLs)),
+		interval: interval,
+	}
+	for region, peerURL := range peerURLs {
+		f.peers[region] = client.NewClient(peerURL, httpClient)
+		f.catalogs[region] = &catalog{
+			services: make(map[string]map[string]registry.HostStatus),
+			lock:     sync.RWMutex{},
+		}
+	}
+	return f
+}
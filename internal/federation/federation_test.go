@@ -0,0 +1,99 @@
+package federation
+
+import (
+	"context"
+	"github.com/mat-sik/eureka-go/internal/client"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Federation_SyncsRemoteCatalog(t *testing.T) {
+	// given
+	ctx := context.Background()
+
+	remoteStore := registry.NewStore()
+	remoteServer := httptest.NewServer(registry.NewHandler(remoteStore))
+	defer remoteServer.Close()
+	remoteClient := client.NewClient(remoteServer.URL, http.DefaultClient)
+
+	fed := NewFederation(map[string]string{"us": remoteServer.URL}, &http.Client{Timeout: time.Second}, time.Hour)
+
+	// when
+	_, syncedBefore := fed.Lookup("us", "one")
+
+	register(t, remoteClient, "one", registry.RegisterInstanceRequest{InstanceID: "a", Host: "10.0.0.1:8080"})
+	fed.syncAll(ctx)
+	afterFull, _ := fed.Lookup("us", "one")
+
+	remoteStore.Put("one", "a", registry.Healthy)
+	register(t, remoteClient, "two", registry.RegisterInstanceRequest{InstanceID: "b", Host: "10.0.0.2:8080"})
+	fed.syncAll(ctx)
+	afterDelta, _ := fed.Lookup("us", "one")
+	two, _ := fed.Lookup("us", "two")
+
+	remoteStore.Remove("two", "b")
+	fed.syncAll(ctx)
+	twoAfterRemove, _ := fed.Lookup("us", "two")
+
+	_, unknownRegion := fed.Lookup("eu", "one")
+
+	// then
+	if syncedBefore {
+		t.Fatalf("lookup before first sync: got %v, want %v", syncedBefore, false)
+	}
+	if len(afterFull) != 1 || afterFull[0].Status != registry.Unknown {
+		t.Fatalf("after full sync: got %v, want one unknown instance", afterFull)
+	}
+	if len(afterDelta) != 1 || afterDelta[0].Status != registry.Healthy {
+		t.Fatalf("after delta sync: got %v, want one healthy instance", afterDelta)
+	}
+	if len(two) != 1 {
+		t.Fatalf("len(two) = %d, want 1", len(two))
+	}
+	if len(twoAfterRemove) != 0 {
+		t.Fatalf("len(two) after remove = %d, want 0", len(twoAfterRemove))
+	}
+	if unknownRegion {
+		t.Fatalf("lookup of unknown region: got %v, want %v", unknownRegion, false)
+	}
+}
+
+func Test_Federation_MirrorsRemoteKV(t *testing.T) {
+	// given
+	ctx := context.Background()
+
+	remoteStore := registry.NewStore()
+	remoteServer := httptest.NewServer(registry.NewHandler(remoteStore))
+	defer remoteServer.Close()
+
+	fed := NewFederation(map[string]string{"us": remoteServer.URL}, &http.Client{Timeout: time.Second}, time.Hour)
+
+	// when
+	_, syncedBefore := fed.LookupKV("us", "")
+
+	if _, err := remoteStore.KV().Put("config/payments/timeout", "5s", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := remoteStore.KV().Put("config/search/timeout", "1s", nil); err != nil {
+		t.Fatal(err)
+	}
+	fed.syncAll(ctx)
+	payments, _ := fed.LookupKV("us", "config/payments/")
+
+	// then
+	if syncedBefore {
+		t.Fatalf("lookup before first sync: got %v, want %v", syncedBefore, false)
+	}
+	if len(payments) != 1 || payments[0].Value != "5s" {
+		t.Fatalf("payments config: got %+v, want one entry with 5s", payments)
+	}
+}
+
+func register(t *testing.T, c client.Client, serviceID string, regReq registry.RegisterInstanceRequest) {
+	if _, err := c.Register(context.Background(), serviceID, regReq); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,84 +2,200 @@ package health
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/mat-sik/eureka-go/internal/registry"
+	"io"
 	"log/slog"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Checker struct {
 	client        *http.Client
 	ticker        *time.Ticker
+	state         *checkerState
 	statusUpdater statusUpdater
 }
 
+type CheckerState struct {
+	Interval            string    `json:"interval"`
+	Timeout             string    `json:"timeout"`
+	ReportTTL           string    `json:"report_ttl"`
+	Rounds              uint64    `json:"rounds"`
+	LastRoundStartedAt  time.Time `json:"last_round_started_at,omitzero"`
+	LastRoundFinishedAt time.Time `json:"last_round_finished_at,omitzero"`
+	LastRoundProbes     int       `json:"last_round_probes"`
+	LastRoundFailures   int       `json:"last_round_failures"`
+}
+
+type checkerState struct {
+	interval  time.Duration
+	timeout   time.Duration
+	reportTTL time.Duration
+	current   CheckerState
+	lock      sync.RWMutex
+}
+
+func (s *checkerState) probeTimeout() time.Duration {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.timeout
+}
+
+func (s *checkerState) currentReportTTL() time.Duration {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.reportTTL
+}
+
+func (s *checkerState) finishRound(startedAt time.Time, finishedAt time.Time, probes int, failures int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.current.Rounds++
+	s.current.LastRoundStartedAt = startedAt
+	s.current.LastRoundFinishedAt = finishedAt
+	s.current.LastRoundProbes = probes
+	s.current.LastRoundFailures = failures
+}
+
 type statusUpdater interface {
-	serviceIDsToHostsGetter
+	serviceIDsToInstancesGetter
 	statusPutter
 }
 
+func (c Checker) Reconfigure(interval time.Duration, timeout time.Duration, reportTTL time.Duration) {
+	c.state.lock.Lock()
+	defer c.state.lock.Unlock()
+
+	c.ticker.Reset(interval)
+	c.state.interval = interval
+	c.state.timeout = timeout
+	c.state.reportTTL = reportTTL
+}
+
+func (c Checker) State() CheckerState {
+	c.state.lock.RLock()
+	defer c.state.lock.RUnlock()
+
+	state := c.state.current
+	state.Interval = c.state.interval.String()
+	state.Timeout = c.state.timeout.String()
+	state.ReportTTL = c.state.reportTTL.String()
+	return state
+}
+
+func (c Checker) Ready() (bool, string) {
+	c.state.lock.RLock()
+	defer c.state.lock.RUnlock()
+
+	if c.state.current.Rounds == 0 {
+		return false, "health checker has not finished its first round"
+	}
+	return true, ""
+}
+
 func (c Checker) Run(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-c.ticker.C:
-			if err := c.checkAll(ctx); err != nil {
-				return err
-			}
+			c.checkAll(ctx)
 		}
 	}
 }
 
-type serviceIDsToHostsGetter interface {
-	GetServiceIDsToHosts() map[string][]string
+type serviceIDsToInstancesGetter interface {
+	GetServiceIDsToInstances() map[string][]registry.Instance
 }
 
-func (c Checker) checkAll(ctx context.Context) error {
-	serviceIDsToHosts := c.statusUpdater.GetServiceIDsToHosts()
+func (c Checker) checkAll(ctx context.Context) {
+	startedAt := time.Now()
+	serviceIDsToInstances := c.statusUpdater.GetServiceIDsToInstances()
 	wg := &sync.WaitGroup{}
-	errCh := make(chan error, jobCount(serviceIDsToHosts))
-	defer close(errCh)
-	for serviceID, hosts := range serviceIDsToHosts {
-		for _, host := range hosts {
+	probes := 0
+	failures := &atomic.Int32{}
+	reportTTL := c.state.currentReportTTL()
+	for serviceID, instances := range serviceIDsToInstances {
+		for _, instance := range instances {
+			if instance.ReportsHealth {
+				if reportTTL > 0 && instance.Status != registry.Down && startedAt.Sub(instance.ReportedAt) > reportTTL {
+					c.markUnreported(serviceID, instance, reportTTL)
+				}
+				continue
+			}
 			wg.Add(1)
-			go c.checkJob(ctx, wg, errCh, serviceID, host)
+			probes++
+			go c.checkJob(ctx, wg, failures, serviceID, instance)
 		}
 	}
 	wg.Wait()
 
-	return collectErrs(errCh)
+	if ctx.Err() == nil {
+		c.state.finishRound(startedAt, time.Now(), probes, int(failures.Load()))
+	}
 }
 
 type statusPutter interface {
-	Put(serviceID string, host string, status registry.Status)
+	PutCheck(serviceID string, instanceID string, check registry.Check)
 }
 
-func (c Checker) checkJob(ctx context.Context, wg *sync.WaitGroup, errCh chan<- error, serviceID string, host string) {
-	slog.Info("running checker job", "serviceID", serviceID, "host", host)
+func (c Checker) checkJob(ctx context.Context, wg *sync.WaitGroup, failures *atomic.Int32, serviceID string, instance registry.Instance) {
+	slog.Info("running checker job", "serviceID", serviceID, "instanceID", instance.ID, "host", instance.Host)
 	defer wg.Done()
-	status, err := c.check(ctx, host)
+	probeCtx, cancel := context.WithTimeout(ctx, c.state.probeTimeout())
+	defer cancel()
+	result, err := Probe(probeCtx, c.client, instance.Host)
+	check := registry.Check{
+		Status:     result.Status,
+		CheckedAt:  time.Now(),
+		Components: result.Components,
+	}
 	if err != nil {
-		errCh <- err
-		return
+		if ctx.Err() != nil {
+			return
+		}
+		failures.Add(1)
+		slog.Warn("checker job failed", "serviceID", serviceID, "instanceID", instance.ID, "host", instance.Host, "err", err)
+		check.Status = registry.Down
+		check.Error = err.Error()
+	} else {
+		slog.Info("checker job finished", "serviceID", serviceID, "instanceID", instance.ID, "host", instance.Host, "status", result.Status)
 	}
-	slog.Info("checker job finished", "serviceID", serviceID, "host", host, "status", status)
-	c.statusUpdater.Put(serviceID, host, status)
+	c.statusUpdater.PutCheck(serviceID, instance.ID, check)
+}
+
+// markUnreported takes an instance down that stopped reporting its own health,
+// e.g. because its agent is gone, as nothing else would ever change its status.
+func (c Checker) markUnreported(serviceID string, instance registry.Instance, reportTTL time.Duration) {
+	slog.Warn("instance stopped reporting health", "serviceID", serviceID, "instanceID", instance.ID, "reportedAt", instance.ReportedAt)
+	c.statusUpdater.PutCheck(serviceID, instance.ID, registry.Check{
+		Status:    registry.Down,
+		CheckedAt: time.Now(),
+		Error:     fmt.Sprintf("no health report for more than %s", reportTTL),
+	})
+}
+
+func Probe(ctx context.Context, client *http.Client, host string) (Result, error) {
+	return ProbeURL(ctx, client, getHealthAddr(host))
 }
 
-func (c Checker) check(ctx context.Context, host string) (registry.Status, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, getHealthAddr(host), nil)
+const maxResponseSize = 1 << 20
+
+func ProbeURL(ctx context.Context, client *http.Client, healthURL string) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, healthURL, nil)
 	if err != nil {
-		return registry.Unknown, err
+		return Result{Status: registry.Unknown}, err
 	}
-	resp, err := c.client.Do(req)
+	req.Header.Set("Accept", "application/health+json, application/json;q=0.9, text/plain;q=0.5")
+	resp, err := client.Do(req)
 	if err != nil {
-		return registry.Unknown, err
+		return Result{Status: registry.Unknown}, err
 	}
 	defer func() {
 		if err = resp.Body.Close(); err != nil {
@@ -87,42 +203,31 @@ func (c Checker) check(ctx context.Context, host string) (registry.Status, error
 		}
 	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return registry.Down, nil
-	}
-
-	var healthResp Response
-	if err = json.NewDecoder(resp.Body).Decode(&healthResp); err != nil {
-		return registry.Unknown, err
-	}
-
-	return healthResp.Status, nil
-}
-
-func jobCount(serviceIDsToHosts map[string][]string) int {
-	count := 0
-	for _, hosts := range serviceIDsToHosts {
-		count += len(hosts)
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return Result{Status: registry.Unknown}, err
 	}
-	return count
-}
 
-func collectErrs(errCh <-chan error) error {
-	errs := make([]error, 0, len(errCh))
-	for i := 0; i < len(errCh); i++ {
-		errs = append(errs, <-errCh)
-	}
-	return errors.Join(errs...)
+	return parseResponse(resp.StatusCode, body)
 }
 
 func getHealthAddr(host string) string {
 	return fmt.Sprintf("http://%s/health", host)
 }
 
-func NewChecker(client *http.Client, statusUpdater statusUpdater, duration time.Duration) Checker {
+// NewChecker probes the instances every interval. Instances that report their
+// own health are not probed but taken down once they report nothing for
+// reportTTL, zero turns that off.
+func NewChecker(client *http.Client, statusUpdater statusUpdater, interval time.Duration, timeout time.Duration, reportTTL time.Duration) Checker {
 	return Checker{
 		client:        client,
 		statusUpdater: statusUpdater,
-		ticker:        time.NewTicker(duration),
+		ticker:        time.NewTicker(interval),
+		state: &checkerState{
+			interval:  interval,
+			timeout:   timeout,
+			reportTTL: reportTTL,
+			lock:      sync.RWMutex{},
+		},
 	}
 }
@@ -23,6 +23,7 @@ func Test_Checker(t *testing.T) {
 	store := registry.NewStore()
 
 	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	eurekaServer := httptest.NewServer(registry.NewHandler(store))
 	defer eurekaServer.Close()
@@ -59,6 +60,8 @@ func Test_Checker(t *testing.T) {
 		client,
 		mock,
 		100*time.Millisecond,
+		time.Second,
+		0,
 	)
 
 	// when
@@ -136,6 +139,97 @@ loop:
 	}
 }
 
+func Test_Checker_UnreachableInstanceMarkedDown(t *testing.T) {
+	// given
+	closedServer := httptest.NewServer(newConstantStatusHealthCheckHandler(registry.Healthy))
+	host := getHost(t, closedServer.URL)
+	closedServer.Close()
+
+	store := registry.NewStoreFrom(map[string]map[string]registry.Instance{
+		"foo": {"a": {ID: "a", Host: host, Status: registry.Healthy}},
+	})
+	checker := NewChecker(client, store, time.Hour, time.Second, 0)
+
+	// when
+	checker.checkAll(context.Background())
+
+	// then
+	got, ok := store.Find("foo", "a")
+	if !ok {
+		t.Fatal("instance a not found")
+	}
+	if got.Status != registry.Down {
+		t.Fatalf("status: got %s, want %s", got.Status, registry.Down)
+	}
+	if got.LastCheck == nil || got.LastCheck.Error == "" {
+		t.Fatalf("last check: got %v, want the probe error", got.LastCheck)
+	}
+}
+
+func Test_Checker_ProbeErrorReleasesLocks(t *testing.T) {
+	// given
+	closedServer := httptest.NewServer(newConstantStatusHealthCheckHandler(registry.Healthy))
+	host := getHost(t, closedServer.URL)
+	closedServer.Close()
+
+	store := registry.NewStoreFrom(map[string]map[string]registry.Instance{
+		"foo": {"a": {ID: "a", Host: host, Status: registry.Healthy}},
+	})
+	body, err := json.Marshal(registry.LockRequest{ServiceID: "foo", InstanceID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	registry.NewHandler(store).ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/v2/locks/leader", bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("acquire: got %v, want %v", recorder.Code, http.StatusOK)
+	}
+	checker := NewChecker(client, store, time.Hour, time.Second, 0)
+
+	// when
+	checker.checkAll(context.Background())
+
+	// then
+	if _, ok := store.Lock("leader"); ok {
+		t.Fatal("lock of the unreachable holder was not released")
+	}
+}
+
+func Test_Checker_UnreportedInstanceMarkedDown(t *testing.T) {
+	// given
+	store := registry.NewStoreFrom(map[string]map[string]registry.Instance{
+		"foo": {
+			"silent": {ID: "silent", Host: "127.0.0.1:1", Status: registry.Healthy, ReportsHealth: true, ReportedAt: time.Now().Add(-time.Minute)},
+			"active": {ID: "active", Host: "127.0.0.1:2", Status: registry.Healthy, ReportsHealth: true, ReportedAt: time.Now()},
+		},
+	})
+	body, err := json.Marshal(registry.LockRequest{ServiceID: "foo", InstanceID: "silent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	registry.NewHandler(store).ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/v2/locks/leader", bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("acquire: got %v, want %v", recorder.Code, http.StatusOK)
+	}
+	checker := NewChecker(client, store, time.Hour, time.Second, 30*time.Second)
+
+	// when
+	checker.checkAll(context.Background())
+
+	// then
+	silent, _ := store.Find("foo", "silent")
+	if silent.Status != registry.Down || silent.LastCheck == nil || silent.LastCheck.Error == "" {
+		t.Fatalf("silent: got %+v, want down with an error", silent)
+	}
+	if active, _ := store.Find("foo", "active"); active.Status != registry.Healthy {
+		t.Fatalf("active: got %s, want %s", active.Status, registry.Healthy)
+	}
+	if _, ok := store.Lock("leader"); ok {
+		t.Fatal("lock of the silent instance was not released")
+	}
+}
+
 func doRegister(t *testing.T, targetURL string, serviceID string, host string) *http.Response {
 	defer buffer.Reset()
 	regReq := registry.RegisterHostRequest{ServiceID: serviceID, Host: host}
@@ -237,11 +331,11 @@ type mockStore struct {
 	lock                    sync.Mutex
 }
 
-func (m *mockStore) GetServiceIDsToHosts() map[string][]string {
-	return m.store.GetServiceIDsToHosts()
+func (m *mockStore) GetServiceIDsToInstances() map[string][]registry.Instance {
+	return m.store.GetServiceIDsToInstances()
 }
 
-func (m *mockStore) Put(serviceID string, host string, status registry.Status) {
+func (m *mockStore) PutCheck(serviceID string, instanceID string, check registry.Check) {
 	defer func() {
 		select {
 		case <-m.ctx.Done():
@@ -252,10 +346,10 @@ func (m *mockStore) Put(serviceID string, host string, status registry.Status) {
 	}()
 
 	m.lock.Lock()
-	m.hostToStatus[host] = append(m.hostToStatus[host], status)
+	m.hostToStatus[instanceID] = append(m.hostToStatus[instanceID], check.Status)
 	m.lock.Unlock()
 
-	m.store.Put(serviceID, host, status)
+	m.store.PutCheck(serviceID, instanceID, check)
 }
 
 func (m *mockStore) getLoggedStatuses() map[string][]registry.Status {
@@ -0,0 +1,162 @@
+package health
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"strings"
+)
+
+var ErrUnrecognizedResponse = errors.New("unrecognized health response")
+
+type Result struct {
+	Status     registry.Status
+	Components map[string]registry.Component
+}
+
+// rawResponse covers the eureka-go format, Spring Boot Actuator (status with
+// nested components) and the IETF health check draft (status with checks).
+type rawResponse struct {
+	Status     string                  `json:"status"`
+	Output     string                  `json:"output"`
+	Components map[string]rawComponent `json:"components"`
+	Checks     map[string][]rawCheck   `json:"checks"`
+}
+
+type rawComponent struct {
+	Status     string                  `json:"status"`
+	Output     string                  `json:"output"`
+	Details    map[string]any          `json:"details"`
+	Components map[string]rawComponent `json:"components"`
+}
+
+type rawCheck struct {
+	ComponentID   string `json:"componentId"`
+	ComponentType string `json:"componentType"`
+	Status        string `json:"status"`
+	Output        string `json:"output"`
+	ObservedValue any    `json:"observedValue"`
+	ObservedUnit  string `json:"observedUnit"`
+}
+
+func parseResponse(statusCode int, body []byte) (Result, error) {
+	success := statusCode >= 200 && statusCode < 300
+	body = bytes.TrimSpace(body)
+
+	if len(body) == 0 || body[0] != '{' {
+		if success && strings.EqualFold(string(body), "OK") {
+			return Result{Status: registry.Healthy}, nil
+		}
+		if !success {
+			return Result{Status: registry.Down}, nil
+		}
+		return Result{Status: registry.Unknown}, fmt.Errorf("%w: %q", ErrUnrecognizedResponse, truncate(string(body)))
+	}
+
+	var raw rawResponse
+	if err := json.Unmarshal(body, &raw); err != nil {
+		if !success {
+			return Result{Status: registry.Down}, nil
+		}
+		return Result{Status: registry.Unknown}, err
+	}
+
+	status, ok := parseStatus(raw.Status)
+	if !ok {
+		if !success {
+			return Result{Status: registry.Down}, nil
+		}
+		return Result{Status: registry.Unknown}, fmt.Errorf("%w: status %q", ErrUnrecognizedResponse, raw.Status)
+	}
+	if !success && (status == registry.Healthy || status == registry.Degraded) {
+		status = registry.Down
+	}
+
+	components := make(map[string]registry.Component)
+	flattenComponents(components, "", raw.Components)
+	for name, checks := range raw.Checks {
+		for i, check := range checks {
+			components[checkName(name, check, i, len(checks))] = check.component()
+		}
+	}
+	if len(components) == 0 {
+		components = nil
+	}
+
+	return Result{Status: status, Components: components}, nil
+}
+
+func parseStatus(raw string) (registry.Status, bool) {
+	switch strings.ToLower(raw) {
+	case "healthy", "up", "pass", "ok":
+		return registry.Healthy, true
+	case "degraded", "warn":
+		return registry.Degraded, true
+	case "down", "fail", "error":
+		return registry.Down, true
+	case "out_of_service":
+		return registry.OutOfService, true
+	case "starting":
+		return registry.Starting, true
+	case "unknown":
+		return registry.Unknown, true
+	default:
+		return registry.Unknown, false
+	}
+}
+
+func flattenComponents(dst map[string]registry.Component, prefix string, components map[string]rawComponent) {
+	for name, component := range components {
+		if prefix != "" {
+			name = prefix + "/" + name
+		}
+		status, _ := parseStatus(component.Status)
+		dst[name] = registry.Component{
+			Status:  status,
+			Output:  component.Output,
+			Details: component.Details,
+		}
+		flattenComponents(dst, name, component.Components)
+	}
+}
+
+func checkName(name string, check rawCheck, index int, count int) string {
+	switch {
+	case count == 1:
+		return name
+	case check.ComponentID != "":
+		return name + "/" + check.ComponentID
+	default:
+		return fmt.Sprintf("%s/%d", name, index)
+	}
+}
+
+func (c rawCheck) component() registry.Component {
+	status, _ := parseStatus(c.Status)
+	component := registry.Component{Status: status, Output: c.Output}
+
+	details := make(map[string]any)
+	if c.ComponentType != "" {
+		details["componentType"] = c.ComponentType
+	}
+	if c.ObservedValue != nil {
+		details["observedValue"] = c.ObservedValue
+	}
+	if c.ObservedUnit != "" {
+		details["observedUnit"] = c.ObservedUnit
+	}
+	if len(details) > 0 {
+		component.Details = details
+	}
+	return component
+}
+
+func truncate(s string) string {
+	const limit = 64
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit] + "..."
+}
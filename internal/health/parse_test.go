@@ -0,0 +1,108 @@
+package health
+
+import (
+	"errors"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"net/http"
+	"testing"
+)
+
+func Test_ParseResponse_SpringBootActuator(t *testing.T) {
+	// given
+	body := []byte(`{
+		"status": "UP",
+		"components": {
+			"db": {"status": "UP", "details": {"database": "PostgreSQL"}},
+			"diskSpace": {"status": "UP", "details": {"free": 1024}},
+			"broker": {"status": "DOWN", "components": {"primary": {"status": "OUT_OF_SERVICE"}}}
+		}
+	}`)
+
+	// when
+	result, err := parseResponse(http.StatusOK, body)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != registry.Healthy {
+		t.Fatalf("status: got %s, want %s", result.Status, registry.Healthy)
+	}
+	if len(result.Components) != 4 {
+		t.Fatalf("len(components) = %d, want 4: %v", len(result.Components), result.Components)
+	}
+	if result.Components["db"].Details["database"] != "PostgreSQL" {
+		t.Fatalf("db details: got %v", result.Components["db"].Details)
+	}
+	if result.Components["broker/primary"].Status != registry.OutOfService {
+		t.Fatalf("nested component: got %s, want %s", result.Components["broker/primary"].Status, registry.OutOfService)
+	}
+}
+
+func Test_ParseResponse_IETFDraft(t *testing.T) {
+	// given
+	body := []byte(`{
+		"status": "warn",
+		"checks": {
+			"cpu:utilization": [
+				{"componentId": "core-0", "status": "pass", "observedValue": 40, "observedUnit": "percent"},
+				{"componentId": "core-1", "status": "warn", "observedValue": 95, "observedUnit": "percent", "output": "hot"}
+			],
+			"cache:connections": [{"status": "pass"}]
+		}
+	}`)
+
+	// when
+	result, err := parseResponse(http.StatusOK, body)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != registry.Degraded {
+		t.Fatalf("status: got %s, want %s", result.Status, registry.Degraded)
+	}
+	core := result.Components["cpu:utilization/core-1"]
+	if core.Status != registry.Degraded || core.Output != "hot" || core.Details["observedUnit"] != "percent" {
+		t.Fatalf("cpu component: got %+v", core)
+	}
+	if result.Components["cache:connections"].Status != registry.Healthy {
+		t.Fatalf("cache component: got %+v", result.Components["cache:connections"])
+	}
+}
+
+func Test_ParseResponse_StatusCodesAndText(t *testing.T) {
+	// given
+	cases := []struct {
+		name       string
+		statusCode int
+		body       string
+		want       registry.Status
+		wantErr    bool
+	}{
+		{"plain ok", http.StatusOK, "OK\n", registry.Healthy, false},
+		{"eureka format", http.StatusOK, `{"status":"out_of_service"}`, registry.OutOfService, false},
+		{"spring down with 503", http.StatusServiceUnavailable, `{"status":"DOWN"}`, registry.Down, false},
+		{"spring out of service with 503", http.StatusServiceUnavailable, `{"status":"OUT_OF_SERVICE"}`, registry.OutOfService, false},
+		{"up contradicted by 500", http.StatusInternalServerError, `{"status":"UP"}`, registry.Down, false},
+		{"plain error page", http.StatusBadGateway, "bad gateway", registry.Down, false},
+		{"unknown text", http.StatusOK, "fine, thanks", registry.Unknown, true},
+		{"unknown status", http.StatusOK, `{"status":"meh"}`, registry.Unknown, true},
+	}
+
+	for _, c := range cases {
+		// when
+		result, err := parseResponse(c.statusCode, []byte(c.body))
+
+		// then
+		if result.Status != c.want {
+			t.Fatalf("%s: status: got %s, want %s", c.name, result.Status, c.want)
+		}
+		if c.wantErr != (err != nil) {
+			t.Fatalf("%s: err: got %v, want error %v", c.name, err, c.wantErr)
+		}
+		if err != nil && c.body[0] != '{' && !errors.Is(err, ErrUnrecognizedResponse) {
+			t.Fatalf("%s: err: got %v, want %v", c.name, err, ErrUnrecognizedResponse)
+		}
+	}
+}
@@ -5,5 +5,6 @@ import (
 )
 
 type Response struct {
-	Status registry.Status `json:"status"`
+	Status  registry.Status `json:"status"`
+	Reasons []string        `json:"reasons,omitempty"`
 }
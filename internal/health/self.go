@@ -0,0 +1,150 @@
+package health
+
+import (
+	"encoding/json"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+)
+
+type Readiness interface {
+	Ready() (bool, string)
+}
+
+type hostStatusesGetter interface {
+	GetAll() map[string][]registry.HostStatus
+}
+
+// Namespace is what the status reports on for one registry namespace.
+type Namespace struct {
+	Store   hostStatusesGetter
+	Checker Checker
+}
+
+type LivenessHandler struct{}
+
+func (h LivenessHandler) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	writeJSON(writer, Response{Status: registry.Healthy}, http.StatusOK)
+}
+
+type ReadinessHandler struct {
+	checks []Readiness
+}
+
+func (h ReadinessHandler) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	if reasons := notReady(h.checks); len(reasons) > 0 {
+		writeJSON(writer, Response{Status: registry.Starting, Reasons: reasons}, http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(writer, Response{Status: registry.Healthy}, http.StatusOK)
+}
+
+type StatusResponse struct {
+	Version           string                  `json:"version"`
+	StartedAt         time.Time               `json:"started_at"`
+	Uptime            string                  `json:"uptime"`
+	Ready             bool                    `json:"ready"`
+	NotReady          []string                `json:"not_ready,omitempty"`
+	Services          int                     `json:"services"`
+	Instances         int                     `json:"instances"`
+	InstancesByStatus map[registry.Status]int `json:"instances_by_status"`
+	Checker           CheckerState            `json:"checker"`
+
+	Namespaces map[string]NamespaceStatus `json:"namespaces"`
+}
+
+type NamespaceStatus struct {
+	Services          int                     `json:"services"`
+	Instances         int                     `json:"instances"`
+	InstancesByStatus map[registry.Status]int `json:"instances_by_status"`
+	Checker           CheckerState            `json:"checker"`
+}
+
+// StatusHandler counts the instances of every namespace. The top-level checker
+// is the one of the default namespace.
+type StatusHandler struct {
+	namespaces map[string]Namespace
+	checks     []Readiness
+	version    string
+	startedAt  time.Time
+}
+
+func (h StatusHandler) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	reasons := notReady(h.checks)
+	resp := StatusResponse{
+		Version:           h.version,
+		StartedAt:         h.startedAt,
+		Uptime:            time.Since(h.startedAt).Round(time.Second).String(),
+		Ready:             len(reasons) == 0,
+		NotReady:          reasons,
+		InstancesByStatus: make(map[registry.Status]int),
+		Checker:           h.namespaces[registry.DefaultNamespace].Checker.State(),
+		Namespaces:        make(map[string]NamespaceStatus, len(h.namespaces)),
+	}
+	for name, namespace := range h.namespaces {
+		status := NamespaceStatus{
+			InstancesByStatus: make(map[registry.Status]int),
+			Checker:           namespace.Checker.State(),
+		}
+		for _, hostStatuses := range namespace.Store.GetAll() {
+			status.Services++
+			for _, hostStatus := range hostStatuses {
+				status.Instances++
+				status.InstancesByStatus[hostStatus.Status]++
+			}
+		}
+		resp.Namespaces[name] = status
+
+		resp.Services += status.Services
+		resp.Instances += status.Instances
+		for hostStatus, count := range status.InstancesByStatus {
+			resp.InstancesByStatus[hostStatus] += count
+		}
+	}
+
+	writeJSON(writer, resp, http.StatusOK)
+}
+
+func notReady(checks []Readiness) []string {
+	var reasons []string
+	for _, check := range checks {
+		if ok, reason := check.Ready(); !ok {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+func writeJSON(writer http.ResponseWriter, body any, status int) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(body); err != nil {
+		slog.Error("Failed to respond", "err:", err)
+	}
+}
+
+// NewHandler serves the registry's own health. The checkers of all namespaces
+// always gate readiness, checks adds the other components that have to be in
+// sync.
+func NewHandler(namespaces map[string]Namespace, version string, startedAt time.Time, checks ...Readiness) http.Handler {
+	checkers := make([]Readiness, 0, len(namespaces))
+	for _, name := range slices.Sorted(maps.Keys(namespaces)) {
+		checkers = append(checkers, namespaces[name].Checker)
+	}
+	checks = append(checkers, checks...)
+
+	mux := http.NewServeMux()
+
+	livenessHandler := &LivenessHandler{}
+	readinessHandler := &ReadinessHandler{checks: checks}
+	statusHandler := &StatusHandler{namespaces: namespaces, checks: checks, version: version, startedAt: startedAt}
+
+	mux.Handle("GET /health", livenessHandler)
+	mux.Handle("GET /ready", readinessHandler)
+	mux.Handle("GET /admin/status", statusHandler)
+
+	return mux
+}
@@ -0,0 +1,100 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeReadiness struct {
+	ready  bool
+	reason string
+}
+
+func (f *fakeReadiness) Ready() (bool, string) {
+	return f.ready, f.reason
+}
+
+func Test_SelfHandler_ReadyAfterFirstRound(t *testing.T) {
+	// given
+	store := registry.NewStore()
+	err := store.Restore(registry.Snapshot{
+		Version: registry.SnapshotVersion,
+		Services: map[string][]registry.Instance{
+			"one": {{ID: "a", Host: "127.0.0.1:1", Status: registry.Healthy, ReportsHealth: true}},
+		},
+	}, registry.Merge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := NewChecker(http.DefaultClient, store, time.Hour, time.Second, 0)
+	teamStore := registry.NewStoreFrom(map[string]map[string]registry.Instance{
+		"two": {
+			"b": {ID: "b", Host: "127.0.0.1:2", Status: registry.Down, ReportsHealth: true},
+			"c": {ID: "c", Host: "127.0.0.1:3", Status: registry.Healthy, ReportsHealth: true},
+		},
+	})
+	teamChecker := NewChecker(http.DefaultClient, teamStore, time.Minute, time.Second, 0)
+	replica := &fakeReadiness{ready: false, reason: "replica is out of sync"}
+	selfHandler := NewHandler(map[string]Namespace{
+		registry.DefaultNamespace: {Store: store, Checker: checker},
+		"team-a":                  {Store: teamStore, Checker: teamChecker},
+	}, "1.2.3", time.Now(), replica)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		selfHandler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		return recorder
+	}
+
+	// when
+	respHealth := get("/health")
+	respBeforeRound := get("/ready")
+	checker.checkAll(context.Background())
+	teamChecker.checkAll(context.Background())
+	respReplicaBehind := get("/ready")
+	replica.ready = true
+	respReady := get("/ready")
+	respStatus := get("/admin/status")
+
+	// then
+	if respHealth.Code != http.StatusOK {
+		t.Fatalf("health: got %v, want %v", respHealth.Code, http.StatusOK)
+	}
+
+	var before Response
+	if err := json.Unmarshal(respBeforeRound.Body.Bytes(), &before); err != nil {
+		t.Fatal(err)
+	}
+	if respBeforeRound.Code != http.StatusServiceUnavailable || len(before.Reasons) != 3 {
+		t.Fatalf("ready before first round: got %v %v, want %v with 3 reasons", respBeforeRound.Code, before.Reasons, http.StatusServiceUnavailable)
+	}
+	if respReplicaBehind.Code != http.StatusServiceUnavailable {
+		t.Fatalf("ready with replica behind: got %v, want %v", respReplicaBehind.Code, http.StatusServiceUnavailable)
+	}
+	if respReady.Code != http.StatusOK {
+		t.Fatalf("ready: got %v, want %v", respReady.Code, http.StatusOK)
+	}
+
+	var status StatusResponse
+	if err := json.Unmarshal(respStatus.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Version != "1.2.3" || !status.Ready {
+		t.Fatalf("status: got version %q ready %v, want 1.2.3 ready", status.Version, status.Ready)
+	}
+	if status.Services != 2 || status.Instances != 3 || status.InstancesByStatus[registry.Healthy] != 2 || status.InstancesByStatus[registry.Down] != 1 {
+		t.Fatalf("status counts: got %+v", status)
+	}
+	if status.Checker.Rounds != 1 || status.Checker.Interval != time.Hour.String() {
+		t.Fatalf("checker state: got %+v", status.Checker)
+	}
+	team := status.Namespaces["team-a"]
+	if team.Services != 1 || team.Instances != 2 || team.InstancesByStatus[registry.Down] != 1 || team.Checker.Interval != time.Minute.String() {
+		t.Fatalf("team-a status: got %+v", team)
+	}
+}
@@ -0,0 +1,163 @@
+package props
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/sethvargo/go-envconfig"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+type Loader struct {
+	path       string
+	flagValues map[string]string
+}
+
+func (l *Loader) Path() string {
+	return l.path
+}
+
+// Load layers the configuration file, the environment and the command-line
+// flags, in that order, over the defaults and validates the result.
+func (l *Loader) Load() (Config, error) {
+	var config Config
+	fileZeros := make(map[string]string)
+	if l.path != "" {
+		tree, err := decodeFile(l.path, &config)
+		if err != nil {
+			return Config{}, err
+		}
+		explicitZeros(reflect.ValueOf(config), tree, fileZeros)
+	}
+
+	// The zero values set in the file look unset to envconfig, which would
+	// replace them with the defaults, so they are looked up like the rest.
+	lookuper := envconfig.MultiLookuper(envconfig.MapLookuper(l.flagValues), envconfig.OsLookuper(), envconfig.MapLookuper(fileZeros))
+	if err := envconfig.ProcessWith(context.Background(), &envconfig.Config{
+		Target:           &config,
+		Lookuper:         lookuper,
+		DefaultOverwrite: true,
+	}); err != nil {
+		return Config{}, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// decodeFile reads TOML from .toml files and YAML from anything else, and also
+// returns the file as a tree of the keys it sets.
+func decodeFile(path string, config *Config) (map[string]any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	tree := make(map[string]any)
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = decodeTOML(content, config, tree)
+	} else {
+		err = decodeYAML(content, config, tree)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	return tree, nil
+}
+
+func decodeYAML(content []byte, config *Config, tree map[string]any) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if err := yaml.Unmarshal(content, &tree); err != nil {
+		return err
+	}
+	return nil
+}
+
+func decodeTOML(content []byte, config *Config, tree map[string]any) error {
+	metadata, err := toml.NewDecoder(bytes.NewReader(content)).Decode(config)
+	if err != nil {
+		return err
+	}
+	if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+		return fmt.Errorf("unknown field %s", undecoded[0])
+	}
+	_, err = toml.NewDecoder(bytes.NewReader(content)).Decode(&tree)
+	return err
+}
+
+// explicitZeros collects the settings the file sets to their zero value, keyed
+// by environment variable.
+func explicitZeros(value reflect.Value, tree map[string]any, zeros map[string]string) {
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		set, ok := tree[name]
+		if !ok {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+		if tag := field.Tag.Get("env"); tag != "" {
+			kind := fieldValue.Kind()
+			if fieldValue.IsZero() && kind != reflect.Slice && kind != reflect.Map {
+				key, _, _ := strings.Cut(tag, ",")
+				zeros[key] = fmt.Sprint(fieldValue.Interface())
+			}
+			continue
+		}
+		if section, ok := set.(map[string]any); ok && fieldValue.Kind() == reflect.Struct {
+			explicitZeros(fieldValue, section, zeros)
+		}
+	}
+}
+
+// NewLoader parses args into a config file path and one flag per environment
+// variable, named after the variable in lower case with dashes, e.g. -check-interval.
+func NewLoader(name string, args []string) (*Loader, error) {
+	loader := &Loader{flagValues: make(map[string]string)}
+
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.StringVar(&loader.path, "config", os.Getenv("CONFIG_FILE"), "path to the YAML or TOML configuration file")
+	for _, key := range envKeys(reflect.TypeOf(Config{})) {
+		flagName := strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+		flags.Func(flagName, "overrides "+key, func(value string) error {
+			loader.flagValues[key] = value
+			return nil
+		})
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	return loader, nil
+}
+
+func envKeys(t reflect.Type) []string {
+	var keys []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if tag := field.Tag.Get("env"); tag != "" {
+			key, _, _ := strings.Cut(tag, ",")
+			keys = append(keys, key)
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			keys = append(keys, envKeys(field.Type)...)
+		}
+	}
+	return keys
+}
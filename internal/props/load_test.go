@@ -0,0 +1,313 @@
+package props
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Load_LayersFileEnvAndFlags(t *testing.T) {
+	// given
+	path := writeConfig(t, `
+server:
+  port: 9090
+  read_timeout: 7s
+checker:
+  interval: 30s
+  timeout: 3s
+security:
+  admin_cidrs: ["10.0.0.0/8"]
+`)
+	t.Setenv("CHECK_INTERVAL", "20s")
+	t.Setenv("CHECK_TIMEOUT", "4s")
+
+	loader, err := NewLoader("test", []string{"-config", path, "-check-timeout", "5s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	config, err := loader.Load()
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Server.Port != 9090 || config.Server.ReadTimeout != 7*time.Second {
+		t.Fatalf("server from file: got %+v", config.Server)
+	}
+	if config.Server.WriteTimeout != 5*time.Second {
+		t.Fatalf("server default: got %v, want %v", config.Server.WriteTimeout, 5*time.Second)
+	}
+	if config.Checker.Interval != 20*time.Second {
+		t.Fatalf("checker interval from env: got %v, want %v", config.Checker.Interval, 20*time.Second)
+	}
+	if config.Checker.Timeout != 5*time.Second {
+		t.Fatalf("checker timeout from flag: got %v, want %v", config.Checker.Timeout, 5*time.Second)
+	}
+	if len(config.Security.AdminCIDRs) != 1 || config.Security.AdminCIDRs[0] != "10.0.0.0/8" {
+		t.Fatalf("admin cidrs: got %v, want [10.0.0.0/8]", config.Security.AdminCIDRs)
+	}
+}
+
+func Test_Load_KeepsExplicitZeros(t *testing.T) {
+	// given
+	path := writeConfig(t, `
+outlier:
+  consecutive_failures: 0
+webhook:
+  dead_letter_size: 0
+audit:
+  log_size: 0
+`)
+	t.Setenv("AUDIT_LOG_SIZE", "50")
+
+	// when
+	config, err := mustLoader(t, path).Load()
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Outlier.ConsecutiveFailures != 0 || config.Webhook.DeadLetterSize != 0 {
+		t.Fatalf("explicit zeros: got %d, %d, want them kept", config.Outlier.ConsecutiveFailures, config.Webhook.DeadLetterSize)
+	}
+	if config.Audit.LogSize != 50 {
+		t.Fatalf("audit log size from env: got %d, want 50", config.Audit.LogSize)
+	}
+	if config.Outlier.MinHosts != 5 || config.Webhook.QueueSize != 256 {
+		t.Fatalf("defaults: got %d, %d, want 5, 256", config.Outlier.MinHosts, config.Webhook.QueueSize)
+	}
+}
+
+func Test_Load_TOML(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	path := filepath.Join(dir, "eureka.toml")
+	content := `
+[server]
+port = 9090
+read_timeout = "7s"
+
+[outlier]
+consecutive_failures = 0
+
+[federation.peers]
+eu = "http://eu.example.com"
+
+[namespaces.team-a]
+max_services = 10
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unknown := filepath.Join(dir, "unknown.toml")
+	if err := os.WriteFile(unknown, []byte("[server]\nprot = 8080\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	config, err := mustLoader(t, path).Load()
+	_, unknownErr := mustLoader(t, unknown).Load()
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Server.Port != 9090 || config.Server.ReadTimeout != 7*time.Second || config.Server.WriteTimeout != 5*time.Second {
+		t.Fatalf("server: got %+v", config.Server)
+	}
+	if config.Outlier.ConsecutiveFailures != 0 {
+		t.Fatalf("explicit zero: got %d, want 0", config.Outlier.ConsecutiveFailures)
+	}
+	if config.Federation.Peers["eu"] != "http://eu.example.com" || config.Namespaces["team-a"].MaxServices != 10 {
+		t.Fatalf("maps: got %+v, %+v", config.Federation.Peers, config.Namespaces)
+	}
+	if unknownErr == nil {
+		t.Fatal("expected error for unknown field")
+	}
+}
+
+func Test_Load_ReturnsValidationErrors(t *testing.T) {
+	// given
+	path := writeConfig(t, `
+server:
+  port: 70000
+limits:
+  reads_per_client_rate: 5
+security:
+  mutation_cidrs: ["not-a-cidr"]
+`)
+	loader, err := NewLoader("test", []string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	_, err = loader.Load()
+
+	// then
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"server.port", "limits.reads_per_client", "security.mutation_cidrs"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func Test_Load_Namespaces(t *testing.T) {
+	// given
+	path := writeConfig(t, `
+namespaces:
+  team-a:
+    max_services: 10
+    limits:
+      mutations_per_client_rate: 2
+      mutations_per_client_burst: 4
+    security:
+      mutation_cidrs: ["10.1.0.0/16"]
+  Team_B:
+    max_instances_per_service: -1
+  default: {}
+`)
+	valid := writeConfig(t, "namespaces:\n  team-a:\n    max_services: 10\n")
+
+	// when
+	_, invalidErr := mustLoader(t, path).Load()
+	config, err := mustLoader(t, valid).Load()
+
+	// then
+	if invalidErr == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"namespaces.Team_B", "namespaces.Team_B.max_instances_per_service", "namespaces.default"} {
+		if !strings.Contains(invalidErr.Error(), want) {
+			t.Fatalf("error %q does not mention %s", invalidErr, want)
+		}
+	}
+	if strings.Contains(invalidErr.Error(), "namespaces.team-a") {
+		t.Fatalf("error %q rejects a valid namespace", invalidErr)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Namespaces["team-a"].MaxServices != 10 {
+		t.Fatalf("namespaces: got %+v", config.Namespaces)
+	}
+}
+
+func Test_Load_RejectsUnknownFields(t *testing.T) {
+	// given
+	path := writeConfig(t, "server:\n  prot: 8080\n")
+	loader, err := NewLoader("test", []string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	_, err = loader.Load()
+
+	// then
+	if err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+}
+
+func Test_Watcher_AppliesOnlySafeSettings(t *testing.T) {
+	// given
+	path := writeConfig(t, "server:\n  port: 9090\nchecker:\n  interval: 30s\n")
+	loader, err := NewLoader("test", []string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := NewWatcher(loader, config, time.Hour)
+	var reloaded []Config
+	watcher.OnReload(func(config Config) {
+		reloaded = append(reloaded, config)
+	})
+
+	// when
+	if err = os.WriteFile(path, []byte("server:\n  port: 9191\nchecker:\n  interval: 1m\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	watcher.reload()
+
+	if err = os.WriteFile(path, []byte("server:\n  port: -1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	watcher.reload()
+
+	// then
+	if len(reloaded) != 1 {
+		t.Fatalf("len(reloaded) = %d, want 1", len(reloaded))
+	}
+	if reloaded[0].Checker.Interval != time.Minute {
+		t.Fatalf("checker interval: got %v, want %v", reloaded[0].Checker.Interval, time.Minute)
+	}
+	if reloaded[0].Server.Port != 9090 {
+		t.Fatalf("server port: got %d, want %d", reloaded[0].Server.Port, 9090)
+	}
+}
+
+func Test_Watcher_ReloadsNamespaceLimitsAndSecurity(t *testing.T) {
+	// given
+	path := writeConfig(t, "namespaces:\n  team-a:\n    max_services: 10\n    limits:\n      reads_per_client_rate: 1\n      reads_per_client_burst: 1\n")
+	loader := mustLoader(t, path)
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := NewWatcher(loader, config, time.Hour)
+	var reloaded []Config
+	watcher.OnReload(func(config Config) {
+		reloaded = append(reloaded, config)
+	})
+
+	// when
+	next := "namespaces:\n  team-a:\n    max_services: 20\n    limits:\n      reads_per_client_rate: 5\n      reads_per_client_burst: 5\n    security:\n      admin_cidrs: [\"10.0.0.0/8\"]\n  team-b: {}\n"
+	if err = os.WriteFile(path, []byte(next), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	watcher.reload()
+
+	// then
+	if len(reloaded) != 1 {
+		t.Fatalf("len(reloaded) = %d, want 1", len(reloaded))
+	}
+	teamA := reloaded[0].Namespaces["team-a"]
+	if teamA.Limits.ReadsPerClientRate != 5 || len(teamA.Security.AdminCIDRs) != 1 {
+		t.Fatalf("team-a: got %+v, want reloaded limits and security", teamA)
+	}
+	if teamA.MaxServices != 10 {
+		t.Fatalf("team-a max services: got %d, want %d", teamA.MaxServices, 10)
+	}
+	if _, ok := reloaded[0].Namespaces["team-b"]; ok {
+		t.Fatal("new namespace was applied without a restart")
+	}
+}
+
+func mustLoader(t *testing.T, path string) *Loader {
+	loader, err := NewLoader("test", []string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loader
+}
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "eureka.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
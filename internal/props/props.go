@@ -1,26 +1,117 @@
 package props
 
 import (
-	"context"
-	"github.com/sethvargo/go-envconfig"
-	"log"
 	"time"
 )
 
+type Config struct {
+	Server     ServerProperties     `yaml:"server" toml:"server"`
+	Checker    CheckerProperties    `yaml:"checker" toml:"checker"`
+	Cache      CacheProperties      `yaml:"cache" toml:"cache"`
+	Region     RegionProperties     `yaml:"region" toml:"region"`
+	Federation FederationProperties `yaml:"federation" toml:"federation"`
+	Webhook    WebhookProperties    `yaml:"webhook" toml:"webhook"`
+	Audit      AuditProperties      `yaml:"audit" toml:"audit"`
+	Limits     LimitProperties      `yaml:"limits" toml:"limits"`
+	Storage    StorageProperties    `yaml:"storage" toml:"storage"`
+	Security   SecurityProperties   `yaml:"security" toml:"security"`
+	Outlier    OutlierProperties    `yaml:"outlier" toml:"outlier"`
+	Drain      DrainProperties      `yaml:"drain" toml:"drain"`
+	Override   OverrideProperties   `yaml:"override" toml:"override"`
+
+	Namespaces map[string]NamespaceProperties `yaml:"namespaces" toml:"namespaces"`
+}
+
 type ServerProperties struct {
-	Port         int           `env:"PORT, default=8080"`
-	ReadTimeout  time.Duration `env:"READ_TIMEOUT, default=5s"`
-	WriteTimeout time.Duration `env:"WRITE_TIMEOUT, default=5s"`
-	IdleTimeout  time.Duration `env:"IDLE_TIMEOUT, default=5m"`
+	Port         int           `yaml:"port" toml:"port" env:"PORT, default=8080"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"READ_TIMEOUT, default=5s"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"WRITE_TIMEOUT, default=5s"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"IDLE_TIMEOUT, default=5m"`
 }
 
-func NewServerProperties() ServerProperties {
-	ctx := context.Background()
+type CheckerProperties struct {
+	Interval  time.Duration `yaml:"interval" toml:"interval" env:"CHECK_INTERVAL, default=10s"`
+	Timeout   time.Duration `yaml:"timeout" toml:"timeout" env:"CHECK_TIMEOUT, default=2s"`
+	ReportTTL time.Duration `yaml:"report_ttl" toml:"report_ttl" env:"CHECK_REPORT_TTL, default=30s"`
+}
 
-	var props ServerProperties
-	if err := envconfig.Process(ctx, &props); err != nil {
-		log.Fatal(err)
-	}
+type CacheProperties struct {
+	ReadOnly        bool          `yaml:"read_only" toml:"read_only" env:"CACHE_READ_ONLY, default=false"`
+	ReadOnlyRefresh time.Duration `yaml:"read_only_refresh" toml:"read_only_refresh" env:"CACHE_READ_ONLY_REFRESH, default=30s"`
+}
+
+type RegionProperties struct {
+	Region string `yaml:"region" toml:"region" env:"REGION, default=default"`
+	Zone   string `yaml:"zone" toml:"zone" env:"ZONE"`
+}
+
+type FederationProperties struct {
+	Peers        map[string]string `yaml:"peers" toml:"peers" env:"FEDERATION_PEERS"`
+	SyncInterval time.Duration     `yaml:"sync_interval" toml:"sync_interval" env:"FEDERATION_SYNC_INTERVAL, default=30s"`
+	Timeout      time.Duration     `yaml:"timeout" toml:"timeout" env:"FEDERATION_TIMEOUT, default=5s"`
+}
+
+type WebhookProperties struct {
+	Timeout        time.Duration `yaml:"timeout" toml:"timeout" env:"WEBHOOK_TIMEOUT, default=5s"`
+	MaxAttempts    int           `yaml:"max_attempts" toml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS, default=5"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" toml:"initial_backoff" env:"WEBHOOK_INITIAL_BACKOFF, default=1s"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" toml:"max_backoff" env:"WEBHOOK_MAX_BACKOFF, default=1m"`
+	QueueSize      int           `yaml:"queue_size" toml:"queue_size" env:"WEBHOOK_QUEUE_SIZE, default=256"`
+	DeadLetterSize int           `yaml:"dead_letter_size" toml:"dead_letter_size" env:"WEBHOOK_DEAD_LETTER_SIZE, default=1000"`
+}
+
+type AuditProperties struct {
+	LogSize        int    `yaml:"log_size" toml:"log_size" env:"AUDIT_LOG_SIZE, default=10000"`
+	File           string `yaml:"file" toml:"file" env:"AUDIT_FILE"`
+	FileMaxSize    int64  `yaml:"file_max_size" toml:"file_max_size" env:"AUDIT_FILE_MAX_SIZE, default=10485760"`
+	FileMaxBackups int    `yaml:"file_max_backups" toml:"file_max_backups" env:"AUDIT_FILE_MAX_BACKUPS, default=5"`
+}
+
+type LimitProperties struct {
+	MutationsPerClientRate   float64 `yaml:"mutations_per_client_rate" toml:"mutations_per_client_rate" env:"LIMIT_MUTATIONS_PER_CLIENT_RATE, default=0"`
+	MutationsPerClientBurst  int     `yaml:"mutations_per_client_burst" toml:"mutations_per_client_burst" env:"LIMIT_MUTATIONS_PER_CLIENT_BURST, default=0"`
+	MutationsPerServiceRate  float64 `yaml:"mutations_per_service_rate" toml:"mutations_per_service_rate" env:"LIMIT_MUTATIONS_PER_SERVICE_RATE, default=0"`
+	MutationsPerServiceBurst int     `yaml:"mutations_per_service_burst" toml:"mutations_per_service_burst" env:"LIMIT_MUTATIONS_PER_SERVICE_BURST, default=0"`
+	ReadsPerClientRate       float64 `yaml:"reads_per_client_rate" toml:"reads_per_client_rate" env:"LIMIT_READS_PER_CLIENT_RATE, default=0"`
+	ReadsPerClientBurst      int     `yaml:"reads_per_client_burst" toml:"reads_per_client_burst" env:"LIMIT_READS_PER_CLIENT_BURST, default=0"`
+}
+
+type StorageProperties struct {
+	MaxServices            int `yaml:"max_services" toml:"max_services" env:"LIMIT_MAX_SERVICES, default=0"`
+	MaxInstancesPerService int `yaml:"max_instances_per_service" toml:"max_instances_per_service" env:"LIMIT_MAX_INSTANCES_PER_SERVICE, default=0"`
+	ChangeLogSize          int `yaml:"change_log_size" toml:"change_log_size" env:"STORE_CHANGE_LOG_SIZE, default=4096"`
+}
+
+type SecurityProperties struct {
+	AdminCIDRs    []string `yaml:"admin_cidrs" toml:"admin_cidrs" env:"SECURITY_ADMIN_CIDRS"`
+	MutationCIDRs []string `yaml:"mutation_cidrs" toml:"mutation_cidrs" env:"SECURITY_MUTATION_CIDRS"`
+}
+
+type OutlierProperties struct {
+	ConsecutiveFailures int           `yaml:"consecutive_failures" toml:"consecutive_failures" env:"OUTLIER_CONSECUTIVE_FAILURES, default=5"`
+	Interval            time.Duration `yaml:"interval" toml:"interval" env:"OUTLIER_INTERVAL, default=10s"`
+	BaseEjectionTime    time.Duration `yaml:"base_ejection_time" toml:"base_ejection_time" env:"OUTLIER_BASE_EJECTION_TIME, default=30s"`
+	MaxEjectionTime     time.Duration `yaml:"max_ejection_time" toml:"max_ejection_time" env:"OUTLIER_MAX_EJECTION_TIME, default=5m"`
+	MaxEjectionPercent  int           `yaml:"max_ejection_percent" toml:"max_ejection_percent" env:"OUTLIER_MAX_EJECTION_PERCENT, default=10"`
+	MinRequestVolume    int           `yaml:"min_request_volume" toml:"min_request_volume" env:"OUTLIER_MIN_REQUEST_VOLUME, default=100"`
+	MinHosts            int           `yaml:"min_hosts" toml:"min_hosts" env:"OUTLIER_MIN_HOSTS, default=5"`
+	StdevFactor         float64       `yaml:"stdev_factor" toml:"stdev_factor" env:"OUTLIER_STDEV_FACTOR, default=1.9"`
+}
+
+type DrainProperties struct {
+	Period        time.Duration `yaml:"period" toml:"period" env:"DRAIN_PERIOD, default=30s"`
+	SweepInterval time.Duration `yaml:"sweep_interval" toml:"sweep_interval" env:"DRAIN_SWEEP_INTERVAL, default=1s"`
+}
+
+type OverrideProperties struct {
+	SweepInterval time.Duration `yaml:"sweep_interval" toml:"sweep_interval" env:"OVERRIDE_SWEEP_INTERVAL, default=1s"`
+}
 
-	return props
+// NamespaceProperties are read from the configuration file only, the default
+// namespace takes its quotas and access rules from the top-level sections.
+type NamespaceProperties struct {
+	MaxServices            int                `yaml:"max_services" toml:"max_services"`
+	MaxInstancesPerService int                `yaml:"max_instances_per_service" toml:"max_instances_per_service"`
+	Limits                 LimitProperties    `yaml:"limits" toml:"limits"`
+	Security               SecurityProperties `yaml:"security" toml:"security"`
 }
@@ -0,0 +1,106 @@
+package props
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"regexp"
+)
+
+// Namespaces are DNS labels, the default namespace is configured through the
+// top-level sections.
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+const defaultNamespace = "default"
+
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port: %d is not a valid port", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "server.read_timeout: must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout: must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout: must be positive")
+
+	check(c.Checker.Interval > 0, "checker.interval: must be positive")
+	check(c.Checker.Timeout > 0, "checker.timeout: must be positive")
+	check(c.Checker.ReportTTL >= 0, "checker.report_ttl: must not be negative")
+
+	check(!c.Cache.ReadOnly || c.Cache.ReadOnlyRefresh > 0, "cache.read_only_refresh: must be positive")
+
+	check(c.Region.Region != "", "region.region: must not be empty")
+	for region, peer := range c.Federation.Peers {
+		target, err := url.Parse(peer)
+		check(err == nil && target.Scheme != "" && target.Host != "", "federation.peers: %s: %q is not a valid url", region, peer)
+	}
+	check(c.Federation.SyncInterval > 0, "federation.sync_interval: must be positive")
+	check(c.Federation.Timeout > 0, "federation.timeout: must be positive")
+
+	check(c.Webhook.Timeout > 0, "webhook.timeout: must be positive")
+	check(c.Webhook.MaxAttempts > 0, "webhook.max_attempts: must be positive")
+	check(c.Webhook.InitialBackoff > 0, "webhook.initial_backoff: must be positive")
+	check(c.Webhook.MaxBackoff >= c.Webhook.InitialBackoff, "webhook.max_backoff: must not be less than webhook.initial_backoff")
+	check(c.Webhook.QueueSize > 0, "webhook.queue_size: must be positive")
+	check(c.Webhook.DeadLetterSize >= 0, "webhook.dead_letter_size: must not be negative")
+
+	check(c.Audit.LogSize >= 0, "audit.log_size: must not be negative")
+	check(c.Audit.FileMaxSize >= 0, "audit.file_max_size: must not be negative")
+	check(c.Audit.FileMaxBackups >= 0, "audit.file_max_backups: must not be negative")
+
+	checkLimits := func(prefix string, limits LimitProperties) {
+		checkRate := func(name string, rate float64, burst int) {
+			check(rate >= 0 && burst >= 0, "%slimits.%s: rate and burst must not be negative", prefix, name)
+			check((rate > 0) == (burst > 0), "%slimits.%s: rate and burst must be set together", prefix, name)
+		}
+		checkRate("mutations_per_client", limits.MutationsPerClientRate, limits.MutationsPerClientBurst)
+		checkRate("mutations_per_service", limits.MutationsPerServiceRate, limits.MutationsPerServiceBurst)
+		checkRate("reads_per_client", limits.ReadsPerClientRate, limits.ReadsPerClientBurst)
+	}
+	checkLimits("", c.Limits)
+
+	check(c.Storage.MaxServices >= 0, "storage.max_services: must not be negative")
+	check(c.Storage.MaxInstancesPerService >= 0, "storage.max_instances_per_service: must not be negative")
+	check(c.Storage.ChangeLogSize > 0, "storage.change_log_size: must be positive")
+
+	checkSecurity := func(prefix string, security SecurityProperties) {
+		for _, cidr := range security.AdminCIDRs {
+			_, err := netip.ParsePrefix(cidr)
+			check(err == nil, "%ssecurity.admin_cidrs: %q is not a valid cidr", prefix, cidr)
+		}
+		for _, cidr := range security.MutationCIDRs {
+			_, err := netip.ParsePrefix(cidr)
+			check(err == nil, "%ssecurity.mutation_cidrs: %q is not a valid cidr", prefix, cidr)
+		}
+	}
+	checkSecurity("", c.Security)
+
+	check(c.Outlier.ConsecutiveFailures >= 0, "outlier.consecutive_failures: must not be negative")
+	check(c.Outlier.Interval > 0, "outlier.interval: must be positive")
+	check(c.Outlier.BaseEjectionTime > 0, "outlier.base_ejection_time: must be positive")
+	check(c.Outlier.MaxEjectionTime >= c.Outlier.BaseEjectionTime, "outlier.max_ejection_time: must not be less than outlier.base_ejection_time")
+	check(c.Outlier.MaxEjectionPercent >= 0 && c.Outlier.MaxEjectionPercent <= 100, "outlier.max_ejection_percent: must be between 0 and 100")
+	check(c.Outlier.MinRequestVolume >= 0, "outlier.min_request_volume: must not be negative")
+	check(c.Outlier.MinHosts >= 0, "outlier.min_hosts: must not be negative")
+	check(c.Outlier.StdevFactor > 0, "outlier.stdev_factor: must be positive")
+
+	check(c.Drain.Period > 0, "drain.period: must be positive")
+	check(c.Drain.SweepInterval > 0, "drain.sweep_interval: must be positive")
+	check(c.Override.SweepInterval > 0, "override.sweep_interval: must be positive")
+
+	for name, namespace := range c.Namespaces {
+		prefix := "namespaces." + name + "."
+		check(namespacePattern.MatchString(name), "namespaces.%s: must be lower case letters, digits and dashes", name)
+		check(name != defaultNamespace, "namespaces.%s: configure the default namespace through the top-level sections", name)
+		check(namespace.MaxServices >= 0, "%smax_services: must not be negative", prefix)
+		check(namespace.MaxInstancesPerService >= 0, "%smax_instances_per_service: must not be negative", prefix)
+		checkLimits(prefix, namespace.Limits)
+		checkSecurity(prefix, namespace.Security)
+	}
+
+	return errors.Join(errs...)
+}
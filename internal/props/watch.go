@@ -0,0 +1,135 @@
+package props
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+type Watcher struct {
+	loader   *Loader
+	current  Config
+	modTime  time.Time
+	interval time.Duration
+	handlers []func(Config)
+}
+
+func (w *Watcher) OnReload(handler func(Config)) {
+	w.handlers = append(w.handlers, handler)
+}
+
+func (w *Watcher) Run(ctx context.Context) error {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-hangups:
+			w.reload()
+		case <-ticker.C:
+			if modTime := w.fileModTime(); modTime.After(w.modTime) {
+				w.modTime = modTime
+				w.reload()
+			}
+		}
+	}
+}
+
+// reload applies only the settings that are safe to change at runtime, the
+// rest keep their startup values until the next restart.
+func (w *Watcher) reload() {
+	next, err := w.loader.Load()
+	if err != nil {
+		slog.Error("failed to reload config, keeping the current one", "path", w.loader.Path(), "err", err)
+		return
+	}
+
+	for _, section := range restartOnlySections(w.current, next) {
+		slog.Warn("config change requires a restart", "section", section)
+	}
+
+	applied := w.current
+	applied.Checker = next.Checker
+	applied.Limits = next.Limits
+	applied.Security = next.Security
+	applied.Namespaces = reloadNamespaces(w.current.Namespaces, next.Namespaces)
+	if reflect.DeepEqual(applied, w.current) {
+		return
+	}
+	w.current = applied
+
+	slog.Info("reloaded config", "path", w.loader.Path())
+	for _, handler := range w.handlers {
+		handler(applied)
+	}
+}
+
+func (w *Watcher) fileModTime() time.Time {
+	if w.loader.Path() == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(w.loader.Path())
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func restartOnlySections(current Config, next Config) []string {
+	var sections []string
+	currentValue := reflect.ValueOf(current)
+	nextValue := reflect.ValueOf(next)
+	for i := range currentValue.NumField() {
+		name := currentValue.Type().Field(i).Name
+		if name == "Checker" || name == "Limits" || name == "Security" {
+			continue
+		}
+		if name == "Namespaces" {
+			if !reflect.DeepEqual(reloadNamespaces(current.Namespaces, next.Namespaces), next.Namespaces) {
+				sections = append(sections, name)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(currentValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			sections = append(sections, name)
+		}
+	}
+	return sections
+}
+
+// reloadNamespaces takes the limits and access rules of the namespaces that
+// exist already, adding or removing a namespace needs a restart.
+func reloadNamespaces(current map[string]NamespaceProperties, next map[string]NamespaceProperties) map[string]NamespaceProperties {
+	if current == nil {
+		return nil
+	}
+	applied := make(map[string]NamespaceProperties, len(current))
+	for name, properties := range current {
+		if nextProperties, ok := next[name]; ok {
+			properties.Limits = nextProperties.Limits
+			properties.Security = nextProperties.Security
+		}
+		applied[name] = properties
+	}
+	return applied
+}
+
+func NewWatcher(loader *Loader, current Config, interval time.Duration) *Watcher {
+	w := &Watcher{
+		loader:   loader,
+		current:  current,
+		interval: interval,
+	}
+	w.modTime = w.fileModTime()
+	return w
+}
@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"net/http"
+	"net/netip"
+	"slices"
+	"sync/atomic"
+)
+
+type ACL struct {
+	AdminCIDRs    []netip.Prefix
+	MutationCIDRs []netip.Prefix
+}
+
+type AccessControl struct {
+	acl atomic.Pointer[ACL]
+}
+
+func (a *AccessControl) Update(acl ACL) {
+	a.acl.Store(&acl)
+}
+
+func (a *AccessControl) Admin(next http.Handler) http.Handler {
+	return aclHandler{next: next, prefixes: func() []netip.Prefix {
+		return a.acl.Load().AdminCIDRs
+	}}
+}
+
+func (a *AccessControl) mutation(next http.Handler) http.Handler {
+	return aclHandler{next: next, prefixes: func() []netip.Prefix {
+		return a.acl.Load().MutationCIDRs
+	}}
+}
+
+func NewAccessControl(acl ACL) *AccessControl {
+	a := &AccessControl{}
+	a.Update(acl)
+	return a
+}
+
+type aclHandler struct {
+	next     http.Handler
+	prefixes func() []netip.Prefix
+}
+
+func (h aclHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if !allowed(h.prefixes(), request) {
+		http.Error(writer, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	h.next.ServeHTTP(writer, request)
+}
+
+func allowed(prefixes []netip.Prefix, request *http.Request) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(clientIP(request))
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	return slices.ContainsFunc(prefixes, func(prefix netip.Prefix) bool {
+		return prefix.Contains(addr)
+	})
+}
@@ -0,0 +1,56 @@
+package registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func Test_AccessControl_RestrictsByCIDR(t *testing.T) {
+	// given
+	access := NewAccessControl(ACL{
+		AdminCIDRs:    []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		MutationCIDRs: []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")},
+	})
+	aclHandler := NewHandler(NewStore(), WithAccessControl(access))
+
+	call := func(method string, target string, remoteAddr string, body string) int {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		aclHandler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// when
+	mutationAllowed := call(http.MethodPut, "/v2/services/one/instances/a", "192.168.1.1:5000", `{"host":"127.0.0.1:8080"}`)
+	mutationDenied := call(http.MethodPut, "/v2/services/one/instances/b", "10.1.1.1:5000", `{"host":"127.0.0.1:8081"}`)
+	readOpen := call(http.MethodGet, "/v2/services", "172.16.0.1:5000", "")
+	adminAllowed := call(http.MethodGet, "/admin/snapshot", "10.1.1.1:5000", "")
+	adminDenied := call(http.MethodGet, "/admin/snapshot", "192.168.1.1:5000", "")
+
+	access.Update(ACL{})
+	adminAfterUpdate := call(http.MethodGet, "/admin/snapshot", "192.168.1.1:5000", "")
+
+	// then
+	if mutationAllowed != http.StatusCreated {
+		t.Fatalf("allowed mutation: got %v, want %v", mutationAllowed, http.StatusCreated)
+	}
+	if mutationDenied != http.StatusForbidden {
+		t.Fatalf("denied mutation: got %v, want %v", mutationDenied, http.StatusForbidden)
+	}
+	if readOpen != http.StatusOK {
+		t.Fatalf("read: got %v, want %v", readOpen, http.StatusOK)
+	}
+	if adminAllowed != http.StatusOK {
+		t.Fatalf("allowed admin: got %v, want %v", adminAllowed, http.StatusOK)
+	}
+	if adminDenied != http.StatusForbidden {
+		t.Fatalf("denied admin: got %v, want %v", adminDenied, http.StatusForbidden)
+	}
+	if adminAfterUpdate != http.StatusOK {
+		t.Fatalf("admin after update: got %v, want %v", adminAfterUpdate, http.StatusOK)
+	}
+}
@@ -0,0 +1,203 @@
+package registry
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAuditLogSize  = 10000
+	auditWriterQueueSize = 1024
+
+	callerHeader = "X-Eureka-Caller"
+)
+
+// Actor made a change. Name is the remote address of the request, or the
+// component for changes the registry makes itself. Claimed is who the caller
+// said it was, which nothing verifies.
+type Actor struct {
+	Name    string
+	Claimed string
+}
+
+var (
+	systemActor  = Actor{Name: "system"}
+	checkerActor = Actor{Name: "health-checker"}
+)
+
+type AuditAction string
+
+const (
+	AuditRegister     AuditAction = "register"
+	AuditUpdate       AuditAction = "update"
+	AuditStatusChange AuditAction = "status_change"
+	AuditOverride     AuditAction = "override"
+	AuditRemove       AuditAction = "remove"
+	AuditKVPut        AuditAction = "kv_put"
+	AuditKVDelete     AuditAction = "kv_delete"
+	AuditKVDeleteTree AuditAction = "kv_delete_tree"
+)
+
+type AuditEntry struct {
+	Time         time.Time   `json:"time"`
+	Actor        string      `json:"actor"`
+	ClaimedActor string      `json:"claimed_actor,omitempty"`
+	Action       AuditAction `json:"action"`
+	Namespace    string      `json:"namespace,omitempty"`
+	ServiceID    string      `json:"service_id"`
+	InstanceID   string      `json:"instance_id"`
+	Host         string      `json:"host"`
+	Key          string      `json:"key,omitempty"`
+	Old          *HostStatus `json:"old,omitempty"`
+	New          *HostStatus `json:"new,omitempty"`
+}
+
+func newAuditEntry(event Event) AuditEntry {
+	entry := AuditEntry{
+		Time:         event.Time,
+		Actor:        event.Actor,
+		ClaimedActor: event.ClaimedActor,
+		Namespace:    event.Namespace,
+		ServiceID:    event.ServiceID,
+		InstanceID:   event.Instance.InstanceID,
+		Host:         event.Instance.Host,
+	}
+
+	switch event.Type {
+	case Registered:
+		entry.Action = AuditRegister
+		entry.New = &event.Instance
+	case Removed:
+		entry.Action = AuditRemove
+		entry.Old = &event.Instance
+	default:
+		entry.Action = AuditUpdate
+		if event.Type == StatusChanged {
+			entry.Action = AuditStatusChange
+		}
+		if !event.Previous.Override.equal(event.Instance.Override) {
+			entry.Action = AuditOverride
+		}
+		entry.Old = event.Previous
+		entry.New = &event.Instance
+	}
+
+	return entry
+}
+
+// auditLog keeps the latest entries in memory and hands every entry to a
+// goroutine writing them to out, so a slow file does not hold up changes. When
+// the writer falls behind by a full queue, entries are left out of the file.
+type auditLog struct {
+	entries []AuditEntry
+	size    int
+	queue   chan AuditEntry
+	written chan struct{}
+	dropped uint64
+	lock    sync.RWMutex
+}
+
+func (a *auditLog) append(event Event) {
+	a.add(newAuditEntry(event))
+}
+
+func (a *auditLog) add(entry AuditEntry) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.size > 0 {
+		if len(a.entries) == a.size {
+			a.entries = a.entries[1:]
+		}
+		a.entries = append(a.entries, entry)
+	}
+
+	if a.queue != nil {
+		select {
+		case a.queue <- entry:
+		default:
+			a.dropped++
+			slog.Warn("dropping audit entry, the audit writer is behind", "action", entry.Action, "serviceID", entry.ServiceID, "instanceID", entry.InstanceID, "dropped", a.dropped)
+		}
+	}
+}
+
+func (a *auditLog) droppedEntries() uint64 {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	return a.dropped
+}
+
+func (a *auditLog) write(out io.Writer, queue <-chan AuditEntry) {
+	defer close(a.written)
+
+	encoder := json.NewEncoder(out)
+	for entry := range queue {
+		if err := encoder.Encode(entry); err != nil {
+			slog.Warn("failed to write audit entry", "serviceID", entry.ServiceID, "instanceID", entry.InstanceID, "err", err)
+		}
+	}
+}
+
+// close waits for the queued entries to be written. Entries appended later are
+// only kept in memory.
+func (a *auditLog) close() {
+	a.lock.Lock()
+	queue := a.queue
+	a.queue = nil
+	a.lock.Unlock()
+
+	if queue != nil {
+		close(queue)
+		<-a.written
+	}
+}
+
+func (a *auditLog) query(serviceID string, since time.Time) []AuditEntry {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	result := make([]AuditEntry, 0)
+	for _, entry := range a.entries {
+		if serviceID != "" && entry.ServiceID != serviceID {
+			continue
+		}
+		if entry.Time.Before(since) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func newAuditLog(size int, out io.Writer) *auditLog {
+	a := &auditLog{
+		entries: make([]AuditEntry, 0, max(min(size, 1024), 0)),
+		size:    size,
+		lock:    sync.RWMutex{},
+	}
+	if out != nil {
+		queue := make(chan AuditEntry, auditWriterQueueSize)
+		a.queue = queue
+		a.written = make(chan struct{})
+		go a.write(out, queue)
+	}
+	return a
+}
+
+// callerActor names the caller by its remote address. The caller header and
+// the basic auth user are recorded as claimed, since neither is checked here.
+func callerActor(request *http.Request) Actor {
+	actor := Actor{Name: request.RemoteAddr}
+	if caller := request.Header.Get(callerHeader); caller != "" {
+		actor.Claimed = caller
+	} else if user, _, ok := request.BasicAuth(); ok && user != "" {
+		actor.Claimed = user
+	}
+	return actor
+}
@@ -0,0 +1,188 @@
+package registry
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_AuditLog_RecordsCallerAndValues(t *testing.T) {
+	// given
+	store := NewStore()
+	auditHandler := NewHandler(store)
+
+	call := func(method string, target string, body string) {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		request.Header.Set(callerHeader, "deployer")
+		recorder := httptest.NewRecorder()
+		auditHandler.ServeHTTP(recorder, request)
+		if recorder.Code >= 300 {
+			t.Fatalf("%s %s: got %d", method, target, recorder.Code)
+		}
+	}
+
+	// when
+	call(http.MethodPut, "/v2/services/one/instances/a", `{"host":"127.0.0.1:8080"}`)
+	call(http.MethodPut, "/v2/services/two/instances/b", `{"host":"127.0.0.1:8081"}`)
+	store.PutCheck("one", "a", Check{Status: Healthy, CheckedAt: time.Now()})
+	call(http.MethodPut, "/admin/services/one/instances/a/override", `{"status":"out_of_service"}`)
+	call(http.MethodDelete, "/v2/services/one/instances/a", "")
+
+	recorder := httptest.NewRecorder()
+	auditHandler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/events?service=one", nil))
+
+	// then
+	var entries []AuditEntry
+	if err := json.Unmarshal(recorder.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+
+	// The caller header is only recorded as claimed, the actor is the remote
+	// address httptest gives every request.
+	caller := Actor{Name: "192.0.2.1:1234", Claimed: "deployer"}
+	want := []struct {
+		action AuditAction
+		actor  Actor
+	}{
+		{AuditRegister, caller},
+		{AuditStatusChange, checkerActor},
+		{AuditOverride, caller},
+		{AuditRemove, caller},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("len(entries) = %d, want %d: %v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		entry := entries[i]
+		if entry.Action != w.action || entry.Actor != w.actor.Name || entry.ClaimedActor != w.actor.Claimed {
+			t.Fatalf("entry %d: got %s by %s claiming %q, want %s by %+v", i, entry.Action, entry.Actor, entry.ClaimedActor, w.action, w.actor)
+		}
+		if entry.ServiceID != "one" || entry.Host != "127.0.0.1:8080" {
+			t.Fatalf("entry %d: got %s/%s, want one/127.0.0.1:8080", i, entry.ServiceID, entry.Host)
+		}
+	}
+	if entries[1].Old.Status != Unknown || entries[1].New.Status != Healthy {
+		t.Fatalf("status change: got %s -> %s, want %s -> %s", entries[1].Old.Status, entries[1].New.Status, Unknown, Healthy)
+	}
+	if entries[3].Old == nil || entries[3].New != nil {
+		t.Fatalf("remove: got old %v, new %v, want only old", entries[3].Old, entries[3].New)
+	}
+}
+
+func Test_AuditLog_FiltersBySince(t *testing.T) {
+	// given
+	store := NewStore()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	// when
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Hour)
+	store.Remove("one", "a")
+
+	recorder := httptest.NewRecorder()
+	NewHandler(store).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/events?since=2026-01-01T12:30:00Z", nil))
+	badRecorder := httptest.NewRecorder()
+	NewHandler(store).ServeHTTP(badRecorder, httptest.NewRequest(http.MethodGet, "/admin/events?since=yesterday", nil))
+
+	// then
+	var entries []AuditEntry
+	if err := json.Unmarshal(recorder.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Action != AuditRemove {
+		t.Fatalf("entries: got %v, want one remove", entries)
+	}
+	if badRecorder.Code != http.StatusBadRequest {
+		t.Fatalf("status code: got %v, want %v", badRecorder.Code, http.StatusBadRequest)
+	}
+}
+
+func Test_AuditLog_WritesRotatingFile(t *testing.T) {
+	// given
+	path := filepath.Join(t.TempDir(), "audit.log")
+	file, err := NewRotatingFile(path, 200, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = file.Close() }()
+	store := NewStore(WithAuditWriter(file))
+
+	// when
+	for _, instanceID := range []string{"a", "b", "c", "d"} {
+		if _, _, err = store.register("one", Instance{ID: instanceID, Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Close()
+
+	// then
+	var lines int
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var entry AuditEntry
+			if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatal(err)
+			}
+			lines++
+		}
+		_ = f.Close()
+	}
+	if lines < 3 {
+		t.Fatalf("lines across files = %d, want at least 3", lines)
+	}
+	if _, err = os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("backup beyond max backups exists: %v", err)
+	}
+}
+
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func Test_AuditLog_DropsEntriesForSlowWriter(t *testing.T) {
+	// given
+	writer := blockingWriter{release: make(chan struct{})}
+	audit := newAuditLog(10, writer)
+	total := auditWriterQueueSize + 10
+
+	// when
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range total {
+			audit.append(Event{Type: Registered, ServiceID: "one", Time: time.Now()})
+		}
+	}()
+
+	// then
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("appending blocked on the audit writer")
+	}
+	// The writer goroutine holds one entry besides the queue.
+	if dropped := audit.droppedEntries(); dropped < uint64(total-auditWriterQueueSize-1) {
+		t.Fatalf("dropped: got %d, want at least %d", dropped, total-auditWriterQueueSize-1)
+	}
+	close(writer.release)
+	audit.close()
+}
@@ -0,0 +1,176 @@
+package registry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type cacheFormat string
+
+const (
+	formatJSON cacheFormat = "json"
+	formatGzip cacheFormat = "gzip"
+)
+
+type cacheKey struct {
+	serviceID string
+	zone      string
+	format    cacheFormat
+}
+
+// cachedResponse is rebuilt when its service changes or when an override or
+// ejection in it runs out, which changes the status without a new version.
+type cachedResponse struct {
+	body      []byte
+	etag      string
+	version   uint64
+	expiresAt time.Time
+	empty     bool
+}
+
+func (r cachedResponse) expired(now time.Time) bool {
+	return !r.expiresAt.IsZero() && !now.Before(r.expiresAt)
+}
+
+func (r cachedResponse) fresh(version uint64, now time.Time) bool {
+	return r.version == version && !r.expired(now)
+}
+
+type ResponseCache struct {
+	store     *Store
+	readWrite map[cacheKey]cachedResponse
+	lock      sync.RWMutex
+	readOnly  atomic.Pointer[map[cacheKey]cachedResponse]
+}
+
+func (c *ResponseCache) get(serviceID string, zone string, format cacheFormat) (cachedResponse, error) {
+	key := cacheKey{serviceID: serviceID, zone: zone, format: format}
+
+	now := c.store.now()
+	if readOnly := c.readOnly.Load(); readOnly != nil {
+		if resp, ok := (*readOnly)[key]; ok && !resp.expired(now) {
+			return resp, nil
+		}
+	}
+
+	// The zone comes from the caller, so only zones the service has instances
+	// in get entries of their own.
+	version, zone := c.store.zonedVersion(serviceID, zone)
+	key = cacheKey{serviceID: serviceID, zone: zone, format: format}
+
+	c.lock.RLock()
+	resp, ok := c.readWrite[key]
+	c.lock.RUnlock()
+	if ok && resp.fresh(version, now) {
+		return resp, nil
+	}
+
+	resp, err := c.build(serviceID, zone, format)
+	if err != nil {
+		return cachedResponse{}, err
+	}
+
+	if resp.empty {
+		return resp, nil
+	}
+
+	c.lock.Lock()
+	if current, ok := c.readWrite[key]; !ok || current.version <= resp.version {
+		c.readWrite[key] = resp
+	}
+	c.lock.Unlock()
+
+	return resp, nil
+}
+
+func (c *ResponseCache) build(serviceID string, zone string, format cacheFormat) (cachedResponse, error) {
+	hostStatuses, version := c.store.getVersioned(serviceID)
+	rankByZone(hostStatuses, zone)
+
+	body, err := json.Marshal(GetHostStatusesResponse{HostStatuses: hostStatuses})
+	if err != nil {
+		return cachedResponse{}, err
+	}
+
+	hash := fnv.New64a()
+	_, _ = hash.Write(body)
+	etag := fmt.Sprintf(`"%x"`, hash.Sum64())
+
+	if format == formatGzip {
+		buffer := &bytes.Buffer{}
+		gzipWriter := gzip.NewWriter(buffer)
+		if _, err = gzipWriter.Write(body); err != nil {
+			return cachedResponse{}, err
+		}
+		if err = gzipWriter.Close(); err != nil {
+			return cachedResponse{}, err
+		}
+		body = buffer.Bytes()
+	}
+
+	return cachedResponse{
+		body:      body,
+		etag:      etag,
+		version:   version,
+		expiresAt: earliestExpiry(hostStatuses),
+		empty:     len(hostStatuses) == 0,
+	}, nil
+}
+
+func earliestExpiry(hostStatuses []HostStatus) time.Time {
+	var earliest time.Time
+	for _, hostStatus := range hostStatuses {
+		for _, expiresAt := range []time.Time{hostStatus.Override.expiresAt(), hostStatus.Ejection.expiresAt()} {
+			if !expiresAt.IsZero() && (earliest.IsZero() || expiresAt.Before(earliest)) {
+				earliest = expiresAt
+			}
+		}
+	}
+	return earliest
+}
+
+func (c *ResponseCache) RunReadOnly(ctx context.Context, interval time.Duration) error {
+	c.refreshReadOnly()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			c.readOnly.Store(nil)
+			return ctx.Err()
+		case <-ticker.C:
+			c.refreshReadOnly()
+		}
+	}
+}
+
+func (c *ResponseCache) refreshReadOnly() {
+	now := c.store.now()
+	c.lock.Lock()
+	for key, resp := range c.readWrite {
+		if !resp.fresh(c.store.serviceVersion(key.serviceID), now) {
+			delete(c.readWrite, key)
+		}
+	}
+	readOnly := maps.Clone(c.readWrite)
+	c.lock.Unlock()
+
+	c.readOnly.Store(&readOnly)
+}
+
+func NewResponseCache(store *Store) *ResponseCache {
+	return &ResponseCache{
+		store:     store,
+		readWrite: make(map[cacheKey]cachedResponse),
+		lock:      sync.RWMutex{},
+	}
+}
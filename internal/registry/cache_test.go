@@ -0,0 +1,205 @@
+package registry
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_GetHostStatuses_NotModified(t *testing.T) {
+	// given
+	store := NewStore()
+	handler := NewHandler(store)
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	respOne := doCachedRequest(handler, "/service-id/one", nil)
+	etag := respOne.Header().Get("ETag")
+	respTwo := doCachedRequest(handler, "/service-id/one", map[string]string{"If-None-Match": etag})
+
+	store.Put("one", "a", Healthy)
+	respThree := doCachedRequest(handler, "/service-id/one", map[string]string{"If-None-Match": etag})
+
+	// then
+	if respOne.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v", respOne.Code, http.StatusOK)
+	}
+	if respTwo.Code != http.StatusNotModified {
+		t.Fatalf("status code: got %v, want %v", respTwo.Code, http.StatusNotModified)
+	}
+	if respThree.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v", respThree.Code, http.StatusOK)
+	}
+
+	var got GetHostStatusesResponse
+	if err := json.Unmarshal(respThree.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.HostStatuses) != 1 || got.HostStatuses[0].Status != Healthy {
+		t.Fatalf("got %v, want one healthy instance", got)
+	}
+}
+
+func Test_GetHostStatuses_Gzip(t *testing.T) {
+	// given
+	store := NewStore()
+	handler := NewHandler(store)
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	resp := doCachedRequest(handler, "/service-id/one", map[string]string{"Accept-Encoding": "gzip, deflate"})
+
+	// then
+	if resp.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding: got %q, want gzip", resp.Header().Get("Content-Encoding"))
+	}
+	gzipReader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(gzipReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got GetHostStatusesResponse
+	if err = json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.HostStatuses) != 1 || got.HostStatuses[0].InstanceID != "a" {
+		t.Fatalf("got %v, want instance a", got)
+	}
+}
+
+func Test_ResponseCache_ReadOnlyServesSnapshot(t *testing.T) {
+	// given
+	store := NewStore()
+	cache := NewResponseCache(store)
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	before, err := cache.get("one", "", formatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.refreshReadOnly()
+	store.Put("one", "a", Healthy)
+	stale, err := cache.get("one", "", formatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.refreshReadOnly()
+	if _, err = cache.get("one", "", formatJSON); err != nil {
+		t.Fatal(err)
+	}
+	cache.refreshReadOnly()
+	fresh, err := cache.get("one", "", formatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	if stale.etag != before.etag {
+		t.Fatalf("read-only layer was not served before refresh")
+	}
+	if fresh.etag == before.etag {
+		t.Fatalf("read-only layer was not refreshed")
+	}
+}
+
+func doCachedRequest(handler http.Handler, target string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func Test_ResponseCache_ExpiresWithOverride(t *testing.T) {
+	// given
+	store := NewStore()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	store.Put("one", "a", Healthy)
+	if _, err := store.setOverride("one", "a", Override{Status: OutOfService, ExpiresAt: now.Add(time.Minute)}, systemActor); err != nil {
+		t.Fatal(err)
+	}
+	cache := NewResponseCache(store)
+
+	// when
+	overridden, overriddenErr := cache.get("one", "", formatJSON)
+	cache.refreshReadOnly()
+	now = now.Add(2 * time.Minute)
+	expired, expiredErr := cache.get("one", "", formatJSON)
+
+	// then
+	if overriddenErr != nil || expiredErr != nil {
+		t.Fatalf("errors: got %v, %v", overriddenErr, expiredErr)
+	}
+	for _, tc := range []struct {
+		resp cachedResponse
+		want Status
+	}{{overridden, OutOfService}, {expired, Healthy}} {
+		var got GetHostStatusesResponse
+		if err := json.Unmarshal(tc.resp.body, &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.HostStatuses) != 1 || got.HostStatuses[0].Status != tc.want {
+			t.Fatalf("got %+v, want one %s instance", got.HostStatuses, tc.want)
+		}
+	}
+}
+
+func Test_ResponseCache_BoundsCallerZones(t *testing.T) {
+	// given
+	store := NewStore()
+	for _, instance := range []Instance{{ID: "a", Host: "127.0.0.1:8080", Zone: "eu-1"}, {ID: "b", Host: "127.0.0.1:8081", Zone: "eu-2"}} {
+		if _, _, err := store.register("one", instance, "", systemActor); err != nil {
+			t.Fatal(err)
+		}
+		store.Put("one", instance.ID, Healthy)
+	}
+	cache := NewResponseCache(store)
+
+	// when
+	for _, zone := range []string{"", "eu-1", "eu-2", "made-up-1", "made-up-2"} {
+		if _, err := cache.get("one", zone, formatJSON); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cache.get("missing", "eu-1", formatJSON); err != nil {
+		t.Fatal(err)
+	}
+	ranked, err := cache.get("one", "eu-2", formatJSON)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cache.readWrite) != 3 {
+		t.Fatalf("cached entries: got %d, want one per known zone and one without", len(cache.readWrite))
+	}
+	var got GetHostStatusesResponse
+	if err = json.Unmarshal(ranked.body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.HostStatuses[0].InstanceID != "b" {
+		t.Fatalf("eu-2 ranking: got %+v, want b first", got.HostStatuses)
+	}
+}
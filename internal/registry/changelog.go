@@ -0,0 +1,140 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultChangeLogSize = 4096
+
+// changeLog hands out versions while the shard of a change is locked, so the
+// versions of a service follow the order its changes were applied in, and
+// takes the events themselves once the shard is unlocked. Events that wait for
+// an earlier version are kept aside, so however many are reserved at once,
+// e.g. by a restore, none is lost to the ring. It also counts the instances by
+// status as of the committed version, which delta responses are hashed from.
+type changeLog struct {
+	events    []Event
+	pending   map[uint64]Event
+	reserved  atomic.Uint64
+	committed uint64
+	counts    map[Status]int
+	lock      sync.RWMutex
+}
+
+func (c *changeLog) reserve() uint64 {
+	return c.reserved.Add(1)
+}
+
+// commit stores the event under its reserved version and passes every event
+// that no longer waits for an earlier one to record, in version order.
+func (c *changeLog) commit(event Event, record func(Event)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.pending[event.Version] = event
+	for {
+		next, ok := c.pending[c.committed+1]
+		if !ok {
+			return
+		}
+		delete(c.pending, next.Version)
+		c.events[c.slot(next.Version)] = next
+		c.committed = next.Version
+		c.count(next)
+		record(next)
+	}
+}
+
+func (c *changeLog) count(event Event) {
+	switch {
+	case event.Type == Registered:
+		c.counts[event.Instance.Status]++
+	case event.Type == Removed:
+		c.counts[event.Instance.Status]--
+	case event.Previous != nil:
+		c.counts[event.Previous.Status]--
+		c.counts[event.Instance.Status]++
+	}
+	for status, count := range c.counts {
+		if count == 0 {
+			delete(c.counts, status)
+		}
+	}
+}
+
+func (c *changeLog) slot(version uint64) uint64 {
+	return (version - 1) % uint64(len(c.events))
+}
+
+func (c *changeLog) current() uint64 {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.committed
+}
+
+// hash returns the committed version together with the reconcile hash of the
+// registry as of that version.
+func (c *changeLog) hash() (uint64, string) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.committed, reconcileHash(c.counts)
+}
+
+func (c *changeLog) since(version uint64) ([]Event, uint64, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if version > c.committed || c.committed-version > uint64(len(c.events)) {
+		return nil, c.committed, false
+	}
+
+	changes := make([]Event, 0, c.committed-version)
+	for v := version + 1; v <= c.committed; v++ {
+		event := c.events[c.slot(v)]
+		if event.Version != v {
+			return nil, c.committed, false
+		}
+		changes = append(changes, event)
+	}
+
+	return changes, c.committed, true
+}
+
+func newChangeLog(size int) *changeLog {
+	return &changeLog{
+		events:  make([]Event, size),
+		pending: make(map[uint64]Event),
+		counts:  make(map[Status]int),
+		lock:    sync.RWMutex{},
+	}
+}
+
+func ReconcileHash(services map[string][]HostStatus) string {
+	counts := make(map[Status]int)
+	for _, hostStatuses := range services {
+		for _, hostStatus := range hostStatuses {
+			counts[hostStatus.Status]++
+		}
+	}
+	return reconcileHash(counts)
+}
+
+func reconcileHash(counts map[Status]int) string {
+	statuses := make([]Status, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	slices.Sort(statuses)
+
+	hash := &strings.Builder{}
+	for _, status := range statuses {
+		_, _ = fmt.Fprintf(hash, "%s_%d_", strings.ToUpper(string(status)), counts[status])
+	}
+	return hash.String()
+}
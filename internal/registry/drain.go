@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrInvalidDrainPeriod = errors.New("invalid drain period")
+
+var drainerActor = Actor{Name: "drainer"}
+
+const (
+	defaultDrainPeriod   = 30 * time.Second
+	defaultDrainInterval = time.Second
+)
+
+type Drain struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+}
+
+func (d *Drain) equal(other *Drain) bool {
+	if d == nil || other == nil {
+		return d == other
+	}
+	return d.Since.Equal(other.Since) && d.Until.Equal(other.Until)
+}
+
+func (d *Drain) expired(now time.Time) bool {
+	return d != nil && !now.Before(d.Until)
+}
+
+// Drainer hides draining instances from healthy lists and removes them once
+// their drain period is over.
+type Drainer struct {
+	store    *Store
+	period   time.Duration
+	interval time.Duration
+}
+
+// Drain starts draining an instance, a zero period uses the drainer's default.
+// Draining an instance that already drains restarts its drain period.
+func (d Drainer) Drain(serviceID string, instanceID string, period time.Duration, actor Actor) (HostStatus, error) {
+	if period < 0 {
+		return HostStatus{}, ErrInvalidDrainPeriod
+	}
+	if period == 0 {
+		period = d.period
+	}
+	now := d.store.now()
+	return d.store.drain(serviceID, instanceID, Drain{Since: now, Until: now.Add(period)}, actor)
+}
+
+func (d Drainer) Cancel(serviceID string, instanceID string, actor Actor) (HostStatus, error) {
+	return d.store.undrain(serviceID, instanceID, actor)
+}
+
+func (d Drainer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			d.store.removeDrained(drainerActor)
+		}
+	}
+}
+
+func NewDrainer(store *Store, period time.Duration, interval time.Duration) Drainer {
+	return Drainer{
+		store:    store,
+		period:   period,
+		interval: interval,
+	}
+}
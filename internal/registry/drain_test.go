@@ -0,0 +1,158 @@
+package registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Drainer_DrainsThenRemoves(t *testing.T) {
+	// given
+	store := NewStore()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	for _, instanceID := range []string{"a", "b"} {
+		if _, _, err := store.register("one", Instance{ID: instanceID, Host: instanceID + ":8080"}, "", systemActor); err != nil {
+			t.Fatal(err)
+		}
+		store.Put("one", instanceID, Healthy)
+	}
+	drainer := NewDrainer(store, time.Minute, time.Second)
+	events, cancel := store.Subscribe(8, nil)
+	defer cancel()
+
+	// when
+	drained, err := drainer.Drain("one", "a", 0, Actor{Name: "deployer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.PutCheck("one", "a", Check{Status: Healthy, CheckedAt: now})
+	stillDraining, _ := store.Find("one", "a")
+
+	now = now.Add(59 * time.Second)
+	earlyRemoved := store.removeDrained(drainerActor)
+	now = now.Add(time.Second)
+	removed := store.removeDrained(drainerActor)
+
+	// then
+	if drained.Status != Draining || drained.Health != Healthy || !drained.Drain.Until.Equal(now) {
+		t.Fatalf("drained: got %+v, want draining until %v", drained, now)
+	}
+	if stillDraining.Status != Draining {
+		t.Fatalf("after health check: got %v, want %v", stillDraining.Status, Draining)
+	}
+	if earlyRemoved != 0 || removed != 1 {
+		t.Fatalf("removed: got %v before and %v after the drain period, want 0 and 1", earlyRemoved, removed)
+	}
+	if _, ok := store.Find("one", "a"); ok {
+		t.Fatal("drained instance is still registered")
+	}
+	if _, ok := store.Find("one", "b"); !ok {
+		t.Fatal("healthy instance was removed")
+	}
+
+	statusChanged := <-events
+	if statusChanged.Type != StatusChanged || statusChanged.Instance.Status != Draining || statusChanged.Actor != "deployer" {
+		t.Fatalf("first event: got %+v, want status change to draining by deployer", statusChanged)
+	}
+	removedEvent := <-events
+	if removedEvent.Type != Removed || removedEvent.Actor != drainerActor.Name {
+		t.Fatalf("second event: got %+v, want removal by %s", removedEvent, drainerActor.Name)
+	}
+}
+
+func Test_Drainer_RegisterKeepsDrain(t *testing.T) {
+	// given
+	store := NewStore()
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "a:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	store.Put("one", "a", Healthy)
+	drainer := NewDrainer(store, time.Minute, time.Second)
+	drained, err := drainer.Drain("one", "a", 0, systemActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	registered, _, err := store.register("one", Instance{ID: "a", Host: "a:8080"}, "", systemActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, _, err := store.register("one", Instance{ID: "a", Host: "b:8080"}, "", systemActor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	if registered.Status != Draining || !registered.Drain.equal(drained.Drain) {
+		t.Fatalf("re-registered: got %+v, want the drain kept", registered)
+	}
+	if moved.Status == Draining || moved.Drain != nil {
+		t.Fatalf("registered on another host: got %+v, want no drain", moved)
+	}
+}
+
+func Test_DrainHandler(t *testing.T) {
+	// given
+	store := NewStore()
+	drainHandler := NewHandler(store, WithDrainer(NewDrainer(store, time.Minute, time.Second)))
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(method string, path string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		drainHandler.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return recorder
+	}
+
+	// when
+	defaultPeriod := call(http.MethodPut, "/v2/services/one/instances/a/drain", "")
+	defaultDrain, _ := store.Find("one", "a")
+	customPeriod := call(http.MethodPut, "/v2/services/one/instances/a/drain", `{"period":"5m"}`)
+	customDrain, _ := store.Find("one", "a")
+	badPeriod := call(http.MethodPut, "/v2/services/one/instances/a/drain", `{"period":"-1s"}`)
+	unknown := call(http.MethodPut, "/v2/services/one/instances/missing/drain", "")
+	cancelled := call(http.MethodDelete, "/v2/services/one/instances/a/drain", "")
+	afterCancel, _ := store.Find("one", "a")
+
+	// then
+	if defaultPeriod.Code != http.StatusOK || customPeriod.Code != http.StatusOK || cancelled.Code != http.StatusOK {
+		t.Fatalf("got %v, %v, %v, want %v", defaultPeriod.Code, customPeriod.Code, cancelled.Code, http.StatusOK)
+	}
+	if got := defaultDrain.Drain.Until.Sub(defaultDrain.Drain.Since); got != time.Minute {
+		t.Fatalf("default period: got %v, want %v", got, time.Minute)
+	}
+	if got := customDrain.Drain.Until.Sub(customDrain.Drain.Since); got != 5*time.Minute {
+		t.Fatalf("custom period: got %v, want %v", got, 5*time.Minute)
+	}
+	if badPeriod.Code != http.StatusBadRequest {
+		t.Fatalf("bad period: got %v, want %v", badPeriod.Code, http.StatusBadRequest)
+	}
+	if unknown.Code != http.StatusNotFound {
+		t.Fatalf("unknown instance: got %v, want %v", unknown.Code, http.StatusNotFound)
+	}
+	if afterCancel.Status == Draining || afterCancel.Drain != nil {
+		t.Fatalf("after cancel: got %+v, want no drain", afterCancel)
+	}
+}
+
+func Test_NewHandler_StartsNoGoroutines(t *testing.T) {
+	// given
+	store := NewStore()
+	before := runtime.NumGoroutine()
+
+	// when
+	for range 10 {
+		NewHandler(store)
+	}
+
+	// then
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("goroutines: got %d after creating handlers, want at most %d", after, before)
+	}
+}
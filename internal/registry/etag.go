@@ -0,0 +1,42 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+func (hs HostStatus) ETag() string {
+	hs.LastCheck = nil
+	body, err := json.Marshal(hs)
+	if err != nil {
+		panic(err)
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write(body)
+	return fmt.Sprintf(`"%x"`, hash.Sum64())
+}
+
+func checkIfMatch(ifMatch string, current HostStatus, exists bool) error {
+	if ifMatch == "" {
+		return nil
+	}
+	if !exists {
+		return ErrPreconditionFailed
+	}
+	if etagMatches(ifMatch, current.ETag()) {
+		return nil
+	}
+	return ErrPreconditionFailed
+}
+
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	Registered    EventType = "registered"
+	Updated       EventType = "updated"
+	StatusChanged EventType = "status_changed"
+	Removed       EventType = "removed"
+)
+
+type Event struct {
+	Version      uint64      `json:"version"`
+	Type         EventType   `json:"type"`
+	Namespace    string      `json:"namespace,omitempty"`
+	ServiceID    string      `json:"service_id"`
+	Instance     HostStatus  `json:"instance"`
+	Previous     *HostStatus `json:"previous,omitempty"`
+	Actor        string      `json:"actor,omitempty"`
+	ClaimedActor string      `json:"claimed_actor,omitempty"`
+	Time         time.Time   `json:"time"`
+}
+
+// subscribers maps every channel to the callback told about the events the
+// channel had no room for, which may be nil.
+type subscribers struct {
+	chans map[chan Event]func(Event)
+	lock  sync.RWMutex
+}
+
+func (s *subscribers) add(buffer int, dropped func(Event)) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	s.lock.Lock()
+	s.chans[ch] = dropped
+	s.lock.Unlock()
+
+	cancel := sync.OnceFunc(func() {
+		s.lock.Lock()
+		delete(s.chans, ch)
+		s.lock.Unlock()
+		close(ch)
+	})
+	return ch, cancel
+}
+
+func (s *subscribers) publish(event Event) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for ch, dropped := range s.chans {
+		select {
+		case ch <- event:
+		default:
+			slog.Warn("dropping registry event for slow subscriber", "type", event.Type, "serviceID", event.ServiceID)
+			if dropped != nil {
+				dropped(event)
+			}
+		}
+	}
+}
+
+func newEvent(serviceID string, previous *HostStatus, next *HostStatus, now time.Time) (Event, bool) {
+	switch {
+	case previous == nil && next == nil:
+		return Event{}, false
+	case previous == nil:
+		return Event{Type: Registered, ServiceID: serviceID, Instance: *next, Time: now}, true
+	case next == nil:
+		return Event{Type: Removed, ServiceID: serviceID, Instance: *previous, Time: now}, true
+	case previous.equal(*next):
+		return Event{}, false
+	case previous.Status != next.Status:
+		return Event{Type: StatusChanged, ServiceID: serviceID, Instance: *next, Previous: previous, Time: now}, true
+	default:
+		return Event{Type: Updated, ServiceID: serviceID, Instance: *next, Previous: previous, Time: now}, true
+	}
+}
+
+func newSubscribers() *subscribers {
+	return &subscribers{
+		chans: make(map[chan Event]func(Event)),
+		lock:  sync.RWMutex{},
+	}
+}
@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"testing"
+)
+
+func Test_Subscribe_ReceivesChanges(t *testing.T) {
+	// given
+	store := NewStore()
+	events, cancel := store.Subscribe(8, nil)
+	defer cancel()
+
+	serviceID := "one"
+	instance := Instance{ID: "a", Host: "127.0.0.1:8080"}
+
+	// when
+	if _, _, err := store.register(serviceID, instance, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	store.Put(serviceID, instance.ID, Healthy)
+	store.Put(serviceID, instance.ID, Healthy)
+	store.Remove(serviceID, instance.ID)
+
+	// then
+	want := []EventType{Registered, StatusChanged, Removed}
+	for _, wantType := range want {
+		event := <-events
+		if event.Type != wantType {
+			t.Fatalf("event type: got %s, want %s", event.Type, wantType)
+		}
+		if event.ServiceID != serviceID || event.Instance.InstanceID != instance.ID {
+			t.Fatalf("event: got %v, want %s/%s", event, serviceID, instance.ID)
+		}
+	}
+	if len(events) != 0 {
+		t.Fatalf("len(events) = %d, want 0", len(events))
+	}
+}
+
+func Test_PutCheck_ErrorMarksDown(t *testing.T) {
+	// given
+	store := NewStore()
+	serviceID := "one"
+	instance := Instance{ID: "a", Host: "127.0.0.1:8080"}
+	if _, _, err := store.register(serviceID, instance, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	store.PutCheck(serviceID, instance.ID, Check{Status: Healthy})
+	store.PutCheck(serviceID, instance.ID, Check{Status: Unknown, Error: "connection refused"})
+
+	// then
+	got, ok := store.Find(serviceID, instance.ID)
+	if !ok {
+		t.Fatalf("instance %s not found", instance.ID)
+	}
+	if got.Health != Down || got.Status != Down {
+		t.Fatalf("status: got %s/%s, want %s", got.Status, got.Health, Down)
+	}
+	if got.LastCheck == nil || got.LastCheck.Error != "connection refused" || got.LastCheck.Status != Down {
+		t.Fatalf("last check: got %v, want error", got.LastCheck)
+	}
+}
+
+func Test_ChangeLog_RecordsInVersionOrder(t *testing.T) {
+	// given
+	changes := newChangeLog(4)
+	first := Event{ServiceID: "one", Version: changes.reserve()}
+	second := Event{ServiceID: "two", Version: changes.reserve()}
+	var recorded []uint64
+	record := func(event Event) {
+		recorded = append(recorded, event.Version)
+	}
+
+	// when
+	changes.commit(second, record)
+	waiting, waitingVersion, _ := changes.since(0)
+	changes.commit(first, record)
+	committed, committedVersion, ok := changes.since(0)
+
+	// then
+	if len(waiting) != 0 || waitingVersion != 0 {
+		t.Fatalf("before the first commit: got %v at %d, want nothing", waiting, waitingVersion)
+	}
+	if !ok || committedVersion != 2 || len(committed) != 2 || committed[0].ServiceID != "one" {
+		t.Fatalf("after both commits: got %v at %d", committed, committedVersion)
+	}
+	if len(recorded) != 2 || recorded[0] != 1 || recorded[1] != 2 {
+		t.Fatalf("recorded: got %v, want [1 2]", recorded)
+	}
+}
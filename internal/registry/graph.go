@@ -0,0 +1,145 @@
+package registry
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CallerServiceHeader = "X-Eureka-Service"
+	maxCallerServiceLen = 256
+	defaultMaxEdges     = 10000
+)
+
+type Edge struct {
+	Consumer  string    `json:"consumer"`
+	Provider  string    `json:"provider"`
+	Requests  uint64    `json:"requests"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type edgeKey struct {
+	consumer string
+	provider string
+}
+
+// Graph records which services look up which others, as reported by the
+// X-Eureka-Service header of lookup requests.
+type Graph struct {
+	edges    map[edgeKey]*Edge
+	maxEdges int
+	evicted  uint64
+	now      func() time.Time
+	lock     sync.Mutex
+}
+
+func (g *Graph) record(consumer string, provider string) {
+	if consumer == "" || provider == "" || consumer == provider || len(consumer) > maxCallerServiceLen {
+		return
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	now := g.now()
+	key := edgeKey{consumer: consumer, provider: provider}
+	edge, ok := g.edges[key]
+	if !ok {
+		if len(g.edges) >= g.maxEdges {
+			g.evictLeastRecent()
+		}
+		edge = &Edge{Consumer: consumer, Provider: provider, FirstSeen: now}
+		g.edges[key] = edge
+	}
+	edge.Requests++
+	edge.LastSeen = now
+}
+
+func (g *Graph) evictLeastRecent() {
+	var oldest *Edge
+	for _, edge := range g.edges {
+		if oldest == nil || edge.LastSeen.Before(oldest.LastSeen) {
+			oldest = edge
+		}
+	}
+	if oldest != nil {
+		delete(g.edges, edgeKey{consumer: oldest.Consumer, provider: oldest.Provider})
+		g.evicted++
+	}
+}
+
+// Evicted returns how many edges were dropped to make room for new ones.
+func (g *Graph) Evicted() uint64 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.evicted
+}
+
+// Edges returns the edges seen within window, or all of them when window is zero.
+func (g *Graph) Edges(window time.Duration) []Edge {
+	return g.edgesWhere(window, func(Edge) bool { return true })
+}
+
+// Consumers returns the edges of services that looked up the provider.
+func (g *Graph) Consumers(provider string, window time.Duration) []Edge {
+	return g.edgesWhere(window, func(edge Edge) bool { return edge.Provider == provider })
+}
+
+func (g *Graph) edgesWhere(window time.Duration, keep func(Edge) bool) []Edge {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	now := g.now()
+	edges := make([]Edge, 0, len(g.edges))
+	for _, edge := range g.edges {
+		if window > 0 && now.Sub(edge.LastSeen) > window {
+			continue
+		}
+		if keep(*edge) {
+			edges = append(edges, *edge)
+		}
+	}
+	slices.SortFunc(edges, func(a, b Edge) int {
+		return cmp.Or(strings.Compare(a.Consumer, b.Consumer), strings.Compare(a.Provider, b.Provider))
+	})
+	return edges
+}
+
+func (g *Graph) track(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		g.record(request.Header.Get(CallerServiceHeader), request.PathValue("serviceID"))
+		handler.ServeHTTP(writer, request)
+	})
+}
+
+func writeDOT(out io.Writer, nodes []string, edges []Edge) error {
+	var builder strings.Builder
+	builder.WriteString("digraph eureka {\n")
+	for _, node := range nodes {
+		fmt.Fprintf(&builder, "  %s;\n", strconv.Quote(node))
+	}
+	for _, edge := range edges {
+		fmt.Fprintf(&builder, "  %s -> %s [label=%q];\n", strconv.Quote(edge.Consumer), strconv.Quote(edge.Provider), strconv.FormatUint(edge.Requests, 10))
+	}
+	builder.WriteString("}\n")
+
+	_, err := io.WriteString(out, builder.String())
+	return err
+}
+
+func NewGraph() *Graph {
+	return &Graph{
+		edges:    make(map[edgeKey]*Edge),
+		maxEdges: defaultMaxEdges,
+		now:      time.Now,
+		lock:     sync.Mutex{},
+	}
+}
@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Graph_RecordsEdges(t *testing.T) {
+	// given
+	graph := NewGraph()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	graph.now = func() time.Time { return now }
+
+	// when
+	graph.record("orders", "payments")
+	graph.record("orders", "payments")
+	graph.record("orders", "orders")
+	graph.record("", "payments")
+	now = now.Add(time.Hour)
+	graph.record("checkout", "payments")
+
+	all := graph.Edges(0)
+	recent := graph.Edges(time.Minute)
+	consumers := graph.Consumers("payments", 0)
+
+	// then
+	want := []Edge{
+		{Consumer: "checkout", Provider: "payments", Requests: 1, FirstSeen: now, LastSeen: now},
+		{Consumer: "orders", Provider: "payments", Requests: 2, FirstSeen: now.Add(-time.Hour), LastSeen: now.Add(-time.Hour)},
+	}
+	if len(all) != len(want) || all[0] != want[0] || all[1] != want[1] {
+		t.Fatalf("edges: got %+v, want %+v", all, want)
+	}
+	if len(recent) != 1 || recent[0].Consumer != "checkout" {
+		t.Fatalf("recent edges: got %+v, want only checkout", recent)
+	}
+	if len(consumers) != 2 {
+		t.Fatalf("consumers: got %+v, want 2", consumers)
+	}
+}
+
+func Test_Graph_EvictsLeastRecentEdge(t *testing.T) {
+	// given
+	graph := NewGraph()
+	graph.maxEdges = 2
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	graph.now = func() time.Time { return now }
+	graph.record("orders", "payments")
+	now = now.Add(time.Minute)
+	graph.record("checkout", "payments")
+	now = now.Add(time.Minute)
+	graph.record("orders", "payments")
+
+	// when
+	now = now.Add(time.Minute)
+	graph.record("billing", "payments")
+
+	// then
+	edges := graph.Edges(0)
+	if len(edges) != 2 || edges[0].Consumer != "billing" || edges[1].Consumer != "orders" {
+		t.Fatalf("edges: got %+v, want billing and orders", edges)
+	}
+	if got := graph.Evicted(); got != 1 {
+		t.Fatalf("evicted: got %d, want 1", got)
+	}
+}
+
+func Test_GraphHandler(t *testing.T) {
+	// given
+	store := NewStore()
+	graph := NewGraph()
+	graphHandler := NewHandler(store, WithGraph(graph))
+	if _, _, err := store.register("payments", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.register("search", Instance{ID: "b", Host: "127.0.0.1:8081"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(target string, caller string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		if caller != "" {
+			request.Header.Set(CallerServiceHeader, caller)
+		}
+		recorder := httptest.NewRecorder()
+		graphHandler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// when
+	call("/v2/services/payments/instances", "orders")
+	call("/v2/services/payments/instances/a", "orders")
+	call("/service-id/payments", "checkout")
+	call("/v2/services/search/instances", "")
+
+	jsonResp := call("/admin/graph", "")
+	dotResp := call("/admin/graph?format=dot", "")
+	consumersResp := call("/admin/services/payments/consumers", "")
+	badFormat := call("/admin/graph?format=svg", "")
+
+	// then
+	var got GraphResponse
+	if err := json.Unmarshal(jsonResp.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got.Nodes, ",") != "checkout,orders,payments,search" {
+		t.Fatalf("nodes: got %v", got.Nodes)
+	}
+	if len(got.Edges) != 2 || got.Edges[1].Consumer != "orders" || got.Edges[1].Requests != 2 {
+		t.Fatalf("edges: got %+v", got.Edges)
+	}
+
+	dot := dotResp.Body.String()
+	if !strings.HasPrefix(dot, "digraph eureka {") || !strings.Contains(dot, `"orders" -> "payments" [label="2"];`) {
+		t.Fatalf("dot: got %q", dot)
+	}
+
+	var consumers ConsumersResponse
+	if err := json.Unmarshal(consumersResp.Body.Bytes(), &consumers); err != nil {
+		t.Fatal(err)
+	}
+	if consumers.ServiceID != "payments" || len(consumers.Consumers) != 2 {
+		t.Fatalf("consumers: got %+v", consumers)
+	}
+	if badFormat.Code != http.StatusBadRequest {
+		t.Fatalf("bad format: got %v, want %v", badFormat.Code, http.StatusBadRequest)
+	}
+}
@@ -2,9 +2,11 @@ package registry
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
+	"strings"
 )
 
 type RegisterHostHandler struct {
@@ -24,8 +26,28 @@ func (h RegisterHostHandler) ServeHTTP(writer http.ResponseWriter, request *http
 		return
 	}
 
-	h.store.addNew(regReq.ServiceID, regReq.Host)
+	instanceID := regReq.InstanceID
+	if instanceID == "" {
+		instanceID = regReq.Host
+	}
+
+	if _, _, err = h.store.register(regReq.ServiceID, Instance{ID: instanceID, Host: regReq.Host, Zone: regReq.Zone, Metadata: regReq.Metadata}, "", callerActor(request)); err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+
+	resp := RegisterHostResponse{InstanceID: instanceID}
+	respBody, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
 	writer.WriteHeader(http.StatusCreated)
+	if _, err = writer.Write(respBody); err != nil {
+		slog.Error("Failed to respond", "response:", resp, "err:", err)
+	}
 }
 
 type RemoveHostHandler struct {
@@ -39,25 +61,74 @@ func (h RemoveHostHandler) ServeHTTP(writer http.ResponseWriter, request *http.R
 		return
 	}
 
+	if remReq.InstanceID != "" {
+		h.store.remove(remReq.ServiceID, remReq.InstanceID, callerActor(request))
+		return
+	}
+
 	_, _, err := net.SplitHostPort(remReq.Host)
 	if err != nil {
 		http.Error(writer, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	h.store.Remove(remReq.ServiceID, remReq.Host)
+	h.store.removeHost(remReq.ServiceID, remReq.Host, callerActor(request))
+}
+
+type RemoteCatalog interface {
+	Regions() []string
+	Lookup(region string, serviceID string) ([]HostStatus, bool)
 }
 
 type GetHostStatusesHandler struct {
-	store *Store
+	store  *Store
+	cache  *ResponseCache
+	region string
+	remote RemoteCatalog
 }
 
 func (h GetHostStatusesHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	name := request.PathValue("serviceID")
+	zone := callerZone(request)
+
+	query := request.URL.Query()
+	region := query.Get("region")
+	fallback := query.Get("fallback")
+	if fallback != "" && fallback != fallbackRemote {
+		http.Error(writer, fmt.Sprintf("unsupported fallback %q", fallback), http.StatusBadRequest)
+		return
+	}
+	pick, err := lookupStrategy(query)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if region != "" && region != h.region {
+		h.serveRemote(writer, region, name, zone, pick)
+		return
+	}
+
+	if fallback == fallbackRemote && h.remote != nil && !anyHealthy(h.store.Get(name)) {
+		for _, remoteRegion := range h.remote.Regions() {
+			if hostStatuses, ok := h.remote.Lookup(remoteRegion, name); ok && anyHealthy(hostStatuses) {
+				rankByZone(hostStatuses, zone)
+				writeJSON(writer, GetHostStatusesResponse{HostStatuses: pick.apply(hostStatuses), Region: remoteRegion}, http.StatusOK)
+				return
+			}
+		}
+	}
+
+	// Weighted picks differ on every request, so they bypass the response cache.
+	if h.cache != nil && pick == nil {
+		h.serveCached(writer, request, name, zone)
+		return
+	}
 
 	hostStatuses := h.store.Get(name)
+	rankByZone(hostStatuses, zone)
 
-	resp := GetHostStatusesResponse{hostStatuses}
+	resp := GetHostStatusesResponse{HostStatuses: pick.apply(hostStatuses)}
 	respBody, err := json.Marshal(resp)
 	if err != nil {
 		http.Error(writer, err.Error(), http.StatusInternalServerError)
@@ -70,16 +141,265 @@ func (h GetHostStatusesHandler) ServeHTTP(writer http.ResponseWriter, request *h
 	}
 }
 
-func NewHandler(store *Store) http.Handler {
+func (h GetHostStatusesHandler) serveCached(writer http.ResponseWriter, request *http.Request, serviceID string, zone string) {
+	format := formatJSON
+	if acceptsGzip(request) {
+		format = formatGzip
+	}
+
+	resp, err := h.cache.get(serviceID, zone, format)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("ETag", resp.etag)
+	writer.Header().Set("Vary", "Accept-Encoding, "+zoneHeader)
+	if etagMatches(request.Header.Get("If-None-Match"), resp.etag) {
+		writer.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	if format == formatGzip {
+		writer.Header().Set("Content-Encoding", "gzip")
+	}
+	if _, err = writer.Write(resp.body); err != nil {
+		slog.Error("Failed to respond", "serviceID:", serviceID, "err:", err)
+	}
+}
+
+func (h GetHostStatusesHandler) serveRemote(writer http.ResponseWriter, region string, serviceID string, zone string, pick picker) {
+	if h.remote == nil {
+		http.Error(writer, fmt.Sprintf("unknown region %q", region), http.StatusNotFound)
+		return
+	}
+
+	hostStatuses, ok := h.remote.Lookup(region, serviceID)
+	if !ok {
+		http.Error(writer, fmt.Sprintf("unknown region %q", region), http.StatusNotFound)
+		return
+	}
+	rankByZone(hostStatuses, zone)
+
+	writeJSON(writer, GetHostStatusesResponse{HostStatuses: pick.apply(hostStatuses), Region: region}, http.StatusOK)
+}
+
+const fallbackRemote = "remote"
+
+func anyHealthy(hostStatuses []HostStatus) bool {
+	for _, hostStatus := range hostStatuses {
+		if hostStatus.Status == Healthy {
+			return true
+		}
+	}
+	return false
+}
+
+func acceptsGzip(request *http.Request) bool {
+	for _, encoding := range strings.Split(request.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.TrimSpace(name) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+type Option func(*options)
+
+type options struct {
+	cache       *ResponseCache
+	region      string
+	remote      RemoteCatalog
+	remoteKV    RemoteKV
+	rateLimiter *RateLimiter
+	access      *AccessControl
+	detector    *OutlierDetector
+	drainer     Drainer
+	graph       *Graph
+}
+
+func WithResponseCache(cache *ResponseCache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+func WithRegion(region string) Option {
+	return func(o *options) {
+		o.region = region
+	}
+}
+
+func WithRemoteCatalog(remote RemoteCatalog) Option {
+	return func(o *options) {
+		o.remote = remote
+	}
+}
+
+func WithRemoteKV(remoteKV RemoteKV) Option {
+	return func(o *options) {
+		o.remoteKV = remoteKV
+	}
+}
+
+func WithRateLimits(rateLimits RateLimits) Option {
+	return WithRateLimiter(NewRateLimiter(rateLimits))
+}
+
+func WithRateLimiter(rateLimiter *RateLimiter) Option {
+	return func(o *options) {
+		o.rateLimiter = rateLimiter
+	}
+}
+
+func WithAccessControl(access *AccessControl) Option {
+	return func(o *options) {
+		o.access = access
+	}
+}
+
+func WithOutlierDetector(detector *OutlierDetector) Option {
+	return func(o *options) {
+		o.detector = detector
+	}
+}
+
+func WithDrainer(drainer Drainer) Option {
+	return func(o *options) {
+		o.drainer = drainer
+	}
+}
+
+func WithGraph(graph *Graph) Option {
+	return func(o *options) {
+		o.graph = graph
+	}
+}
+
+func NewHandler(store *Store, opts ...Option) http.Handler {
+	o := options{
+		cache:       NewResponseCache(store),
+		rateLimiter: NewRateLimiter(RateLimits{}),
+		access:      NewAccessControl(ACL{}),
+		graph:       NewGraph(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// The handler starts nothing in the background, the caller runs the
+	// detector and drainer it passes in. Unless they run, drained instances are
+	// never removed and no event tells when an ejection ends.
+	if o.detector == nil {
+		o.detector = NewOutlierDetector(store, DefaultOutlierPolicy())
+	}
+	if o.drainer.store == nil {
+		o.drainer = NewDrainer(store, defaultDrainPeriod, defaultDrainInterval)
+	}
+
+	mutation := func(handler http.Handler) http.Handler {
+		return o.access.mutation(o.rateLimiter.mutation(handler))
+	}
+	clientMutation := func(handler http.Handler) http.Handler {
+		return o.access.mutation(o.rateLimiter.clientMutation(handler))
+	}
+	read := o.rateLimiter.read
+	lookup := func(handler http.Handler) http.Handler {
+		return read(o.graph.track(handler))
+	}
+	admin := o.access.Admin
+
 	mux := http.NewServeMux()
 
 	registerIPHandler := &RegisterHostHandler{store: store}
 	removeIPHandler := &RemoveHostHandler{store: store}
-	getIPHandler := &GetHostStatusesHandler{store: store}
+	getIPHandler := &GetHostStatusesHandler{store: store, cache: o.cache, region: o.region, remote: o.remote}
+
+	mux.Handle("POST /service-id/register", mutation(registerIPHandler))
+	mux.Handle("POST /service-id/remove", mutation(removeIPHandler))
+	mux.Handle("GET /service-id/{serviceID}", lookup(getIPHandler))
+
+	putInstanceHandler := &PutInstanceHandler{store: store}
+	postInstanceHandler := &PostInstanceHandler{store: store}
+	putHealthHandler := &PutHealthHandler{store: store}
+	deleteInstanceHandler := &DeleteInstanceHandler{store: store}
+	getInstanceHandler := &GetInstanceHandler{store: store}
+
+	getServicesHandler := &GetServicesHandler{store: store}
+	eventsHandler := &EventsHandler{store: store}
+
+	mux.Handle("GET /v2/services", read(getServicesHandler))
+	mux.Handle("GET /v2/events", read(eventsHandler))
+	mux.Handle("GET /v2/services/{serviceID}/instances", lookup(getIPHandler))
+	mux.Handle("POST /v2/services/{serviceID}/instances", mutation(postInstanceHandler))
+	mux.Handle("PUT /v2/services/{serviceID}/instances/{instanceID}", mutation(putInstanceHandler))
+	mux.Handle("DELETE /v2/services/{serviceID}/instances/{instanceID}", mutation(deleteInstanceHandler))
+	mux.Handle("GET /v2/services/{serviceID}/instances/{instanceID}", lookup(getInstanceHandler))
+	mux.Handle("PUT /v2/services/{serviceID}/instances/{instanceID}/health", mutation(putHealthHandler))
+
+	reportCallHandler := &ReportCallHandler{detector: o.detector}
+
+	mux.Handle("POST /v2/services/{serviceID}/reports", mutation(reportCallHandler))
+
+	drainHandler := &DrainHandler{drainer: o.drainer}
+	cancelDrainHandler := &CancelDrainHandler{drainer: o.drainer}
+
+	mux.Handle("PUT /v2/services/{serviceID}/instances/{instanceID}/drain", mutation(drainHandler))
+	mux.Handle("DELETE /v2/services/{serviceID}/instances/{instanceID}/drain", mutation(cancelDrainHandler))
+
+	setOverrideHandler := &SetOverrideHandler{store: store}
+	clearOverrideHandler := &ClearOverrideHandler{store: store}
+
+	mux.Handle("PUT /admin/services/{serviceID}/instances/{instanceID}/override", admin(setOverrideHandler))
+	mux.Handle("DELETE /admin/services/{serviceID}/instances/{instanceID}/override", admin(clearOverrideHandler))
+
+	setWeightHandler := &SetWeightHandler{store: store}
+	setVersionWeightHandler := &SetVersionWeightHandler{store: store}
+
+	mux.Handle("PUT /admin/services/{serviceID}/instances/{instanceID}/weight", admin(setWeightHandler))
+	mux.Handle("PUT /admin/services/{serviceID}/versions/{version}/weight", admin(setVersionWeightHandler))
+
+	getKVHandler := &GetKVHandler{kv: store.KV(), region: o.region, remote: o.remoteKV}
+	putKVHandler := &PutKVHandler{store: store}
+	deleteKVHandler := &DeleteKVHandler{store: store}
+
+	mux.Handle("GET /v2/kv/{key...}", read(getKVHandler))
+	mux.Handle("PUT /v2/kv/{key...}", clientMutation(putKVHandler))
+	mux.Handle("DELETE /v2/kv/{key...}", clientMutation(deleteKVHandler))
+
+	getLocksHandler := &GetLocksHandler{store: store}
+	getLockHandler := &GetLockHandler{store: store}
+	acquireLockHandler := &AcquireLockHandler{store: store}
+	releaseLockHandler := &ReleaseLockHandler{store: store}
+
+	mux.Handle("GET /v2/locks", read(getLocksHandler))
+	mux.Handle("GET /v2/locks/{name}", read(getLockHandler))
+	mux.Handle("PUT /v2/locks/{name}", clientMutation(acquireLockHandler))
+	mux.Handle("DELETE /v2/locks/{name}", clientMutation(releaseLockHandler))
+
+	getAppsHandler := &GetAppsHandler{store: store}
+	getDeltaHandler := &GetDeltaHandler{store: store}
+
+	mux.Handle("GET /apps", read(getAppsHandler))
+	mux.Handle("GET /apps/delta", read(getDeltaHandler))
+
+	snapshotHandler := &SnapshotHandler{store: store}
+	restoreHandler := &RestoreHandler{store: store}
+
+	mux.Handle("GET /admin/snapshot", admin(snapshotHandler))
+	mux.Handle("POST /admin/restore", admin(restoreHandler))
+
+	auditLogHandler := &AuditLogHandler{store: store}
+
+	mux.Handle("GET /admin/events", admin(auditLogHandler))
+
+	graphHandler := &GraphHandler{store: store, graph: o.graph}
+	consumersHandler := &ConsumersHandler{graph: o.graph}
 
-	mux.Handle("POST /service-id/register", registerIPHandler)
-	mux.Handle("POST /service-id/remove", removeIPHandler)
-	mux.Handle("GET /service-id/{serviceID}", getIPHandler)
+	mux.Handle("GET /admin/graph", admin(graphHandler))
+	mux.Handle("GET /admin/services/{serviceID}/consumers", admin(consumersHandler))
 
 	return mux
 }
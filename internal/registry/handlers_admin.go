@@ -0,0 +1,299 @@
+package registry
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+)
+
+type SetOverrideHandler struct {
+	store *Store
+}
+
+func (h SetOverrideHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+	instanceID := request.PathValue("instanceID")
+
+	var overrideReq SetOverrideRequest
+	if err := json.NewDecoder(request.Body).Decode(&overrideReq); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	override := Override{Status: overrideReq.Status}
+	if overrideReq.TTL != "" {
+		ttl, err := time.ParseDuration(overrideReq.TTL)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		override.ExpiresAt = h.store.now().Add(ttl)
+	}
+
+	hostStatus, err := h.store.setOverride(serviceID, instanceID, override, callerActor(request))
+	if err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+
+	writeHostStatus(writer, hostStatus, http.StatusOK)
+}
+
+type ClearOverrideHandler struct {
+	store *Store
+}
+
+func (h ClearOverrideHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+	instanceID := request.PathValue("instanceID")
+
+	hostStatus, err := h.store.clearOverride(serviceID, instanceID, callerActor(request))
+	if err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+
+	writeHostStatus(writer, hostStatus, http.StatusOK)
+}
+
+type SetWeightHandler struct {
+	store *Store
+}
+
+func (h SetWeightHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+	instanceID := request.PathValue("instanceID")
+
+	var weightReq SetWeightRequest
+	if err := json.NewDecoder(request.Body).Decode(&weightReq); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hostStatus, err := h.store.setWeight(serviceID, instanceID, weightReq.Weight, callerActor(request))
+	if err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+
+	writeHostStatus(writer, hostStatus, http.StatusOK)
+}
+
+type SetVersionWeightHandler struct {
+	store *Store
+}
+
+func (h SetVersionWeightHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+	version := request.PathValue("version")
+
+	var weightReq SetWeightRequest
+	if err := json.NewDecoder(request.Body).Decode(&weightReq); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hostStatuses, err := h.store.setVersionWeight(serviceID, version, weightReq.Weight, callerActor(request))
+	if err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+
+	writeJSON(writer, GetHostStatusesResponse{HostStatuses: hostStatuses}, http.StatusOK)
+}
+
+type SnapshotHandler struct {
+	store *Store
+}
+
+func (h SnapshotHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	format := request.URL.Query().Get("format")
+	if format != "" && format != snapshotFormatJSON && format != snapshotFormatGzip {
+		http.Error(writer, fmt.Sprintf("unknown snapshot format %q", format), http.StatusBadRequest)
+		return
+	}
+
+	snapshot := h.store.Snapshot()
+
+	var out io.Writer = writer
+	if format == snapshotFormatGzip {
+		gzipWriter := gzip.NewWriter(writer)
+		defer func() {
+			if err := gzipWriter.Close(); err != nil {
+				slog.Error("Failed to finish snapshot", "err:", err)
+			}
+		}()
+		out = gzipWriter
+
+		writer.Header().Set("Content-Type", "application/gzip")
+		writer.Header().Set("Content-Disposition", `attachment; filename="eureka-snapshot.json.gz"`)
+	} else {
+		writer.Header().Set("Content-Type", "application/json")
+	}
+
+	if err := json.NewEncoder(out).Encode(snapshot); err != nil {
+		slog.Error("Failed to write snapshot", "err:", err)
+	}
+}
+
+type RestoreHandler struct {
+	store *Store
+}
+
+func (h RestoreHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	mode := RestoreMode(request.URL.Query().Get("mode"))
+	if mode == "" {
+		mode = Merge
+	}
+
+	body := bufio.NewReader(request.Body)
+	var in io.Reader = body
+	if magic, err := body.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		in = gzipReader
+	}
+
+	var snapshot Snapshot
+	if err := json.NewDecoder(in).Decode(&snapshot); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.restore(snapshot, mode, callerActor(request)); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := RestoreResponse{Services: len(snapshot.Services), Keys: len(snapshot.KV)}
+	for _, instances := range snapshot.Services {
+		resp.Instances += len(instances)
+	}
+	respBody, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	if _, err = writer.Write(respBody); err != nil {
+		slog.Error("Failed to respond", "response:", resp, "err:", err)
+	}
+}
+
+const (
+	snapshotFormatJSON = "json"
+	snapshotFormatGzip = "gzip"
+)
+
+type AuditLogHandler struct {
+	store *Store
+}
+
+func (h AuditLogHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.URL.Query().Get("service")
+
+	var since time.Time
+	if rawSince := request.URL.Query().Get("since"); rawSince != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, rawSince); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries := h.store.AuditLog(serviceID, since)
+
+	writer.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(writer).Encode(entries); err != nil {
+		slog.Error("Failed to write audit log", "err:", err)
+	}
+}
+
+type GraphHandler struct {
+	store *Store
+	graph *Graph
+}
+
+func (h GraphHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	format := request.URL.Query().Get("format")
+	if format != "" && format != graphFormatJSON && format != graphFormatDOT {
+		http.Error(writer, fmt.Sprintf("unknown graph format %q", format), http.StatusBadRequest)
+		return
+	}
+	window, err := parseWindow(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	edges := h.graph.Edges(window)
+	nodes := graphNodes(h.store.GetServiceIDsToHosts(), edges)
+
+	if format == graphFormatDOT {
+		writer.Header().Set("Content-Type", "text/vnd.graphviz")
+		if err = writeDOT(writer, nodes, edges); err != nil {
+			slog.Error("Failed to write graph", "err:", err)
+		}
+		return
+	}
+	writeJSON(writer, GraphResponse{Nodes: nodes, Edges: edges, EvictedEdges: h.graph.Evicted()}, http.StatusOK)
+}
+
+type ConsumersHandler struct {
+	graph *Graph
+}
+
+func (h ConsumersHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+
+	window, err := parseWindow(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(writer, ConsumersResponse{ServiceID: serviceID, Consumers: h.graph.Consumers(serviceID, window)}, http.StatusOK)
+}
+
+const (
+	graphFormatJSON = "json"
+	graphFormatDOT  = "dot"
+)
+
+func parseWindow(request *http.Request) (time.Duration, error) {
+	rawWindow := request.URL.Query().Get("window")
+	if rawWindow == "" {
+		return 0, nil
+	}
+	window, err := time.ParseDuration(rawWindow)
+	if err != nil {
+		return 0, err
+	}
+	if window < 0 {
+		return 0, fmt.Errorf("window %q must not be negative", rawWindow)
+	}
+	return window, nil
+}
+
+func graphNodes(serviceIDsToHosts map[string][]string, edges []Edge) []string {
+	nodes := make(map[string]struct{}, len(serviceIDsToHosts))
+	for serviceID := range serviceIDsToHosts {
+		nodes[serviceID] = struct{}{}
+	}
+	for _, edge := range edges {
+		nodes[edge.Consumer] = struct{}{}
+		nodes[edge.Provider] = struct{}{}
+	}
+	return slices.Sorted(maps.Keys(nodes))
+}
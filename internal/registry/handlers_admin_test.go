@@ -0,0 +1,122 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_SetOverride_TakesPrecedenceOverHealth(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	serviceID := "one"
+	host := "127.0.0.1:8080"
+	instanceURL := fmt.Sprintf("/v2/services/%s/instances/%s", serviceID, host)
+	overrideURL := fmt.Sprintf("/admin/services/%s/instances/%s/override", serviceID, host)
+
+	// when
+	respPut := doHeaderRequest(http.MethodPut, instanceURL, nil)
+
+	testStore.Put(serviceID, host, Healthy)
+
+	respOverride := doRequest(t, http.MethodPut, overrideURL, SetOverrideRequest{Status: OutOfService})
+	respGet := doHeaderRequest(http.MethodGet, instanceURL, nil)
+
+	// then
+	if respPut.Code != http.StatusCreated {
+		t.Fatalf("status code: got %v, want %v", respPut.Code, http.StatusCreated)
+	}
+	if respOverride.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v", respOverride.Code, http.StatusOK)
+	}
+
+	var got HostStatus
+	if err := json.Unmarshal(respGet.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != OutOfService {
+		t.Fatalf("status: got %s, want %s", got.Status, OutOfService)
+	}
+	if got.Health != Healthy {
+		t.Fatalf("health: got %s, want %s", got.Health, Healthy)
+	}
+	if got.Override == nil || got.Override.Status != OutOfService {
+		t.Fatalf("override: got %v, want %s", got.Override, OutOfService)
+	}
+}
+
+func Test_ClearOverride_RestoresHealth(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	serviceID := "one"
+	host := "127.0.0.1:8080"
+	instanceURL := fmt.Sprintf("/v2/services/%s/instances/%s", serviceID, host)
+	overrideURL := fmt.Sprintf("/admin/services/%s/instances/%s/override", serviceID, host)
+
+	// when
+	doHeaderRequest(http.MethodPut, instanceURL, nil)
+	doRequest(t, http.MethodPut, overrideURL, SetOverrideRequest{Status: Starting, TTL: "1h"})
+	respClear := doHeaderRequest(http.MethodDelete, overrideURL, nil)
+
+	// then
+	if respClear.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v", respClear.Code, http.StatusOK)
+	}
+
+	var got HostStatus
+	if err := json.Unmarshal(respClear.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != Unknown || got.Override != nil {
+		t.Fatalf("got %v, want status %s without override", got, Unknown)
+	}
+}
+
+func Test_SetOverride_InvalidStatus(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	serviceID := "one"
+	host := "127.0.0.1:8080"
+	instanceURL := fmt.Sprintf("/v2/services/%s/instances/%s", serviceID, host)
+	overrideURL := fmt.Sprintf("/admin/services/%s/instances/%s/override", serviceID, host)
+
+	// when
+	doHeaderRequest(http.MethodPut, instanceURL, nil)
+	resp := doRequest(t, http.MethodPut, overrideURL, SetOverrideRequest{Status: "maintenance"})
+
+	// then
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusBadRequest)
+	}
+}
+
+func Test_Override_ExpiresAfterTTL(t *testing.T) {
+	// given
+	now := time.Now()
+	instance := Instance{
+		ID:       "one",
+		Host:     "127.0.0.1:8080",
+		Status:   Healthy,
+		Override: &Override{Status: OutOfService, ExpiresAt: now.Add(time.Minute)},
+	}
+
+	// when
+	before := instance.hostStatus(now)
+	after := instance.hostStatus(now.Add(2 * time.Minute))
+
+	// then
+	if before.Status != OutOfService {
+		t.Fatalf("status before expiry: got %s, want %s", before.Status, OutOfService)
+	}
+	if after.Status != Healthy || after.Override != nil {
+		t.Fatalf("status after expiry: got %v, want %s without override", after, Healthy)
+	}
+}
@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type GetAppsHandler struct {
+	store *Store
+}
+
+func (h GetAppsHandler) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	services, version := h.store.GetAllVersioned()
+
+	resp := AppsResponse{
+		Version:  version,
+		Hash:     ReconcileHash(services),
+		Services: services,
+	}
+	writeJSON(writer, resp, http.StatusOK)
+}
+
+type GetDeltaHandler struct {
+	store *Store
+}
+
+func (h GetDeltaHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	since, err := strconv.ParseUint(request.URL.Query().Get("since"), 10, 64)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	version, hash := h.store.changes.hash()
+	changes, _, ok := h.store.ChangesSince(since)
+	if !ok || since > version {
+		writeJSON(writer, DeltaResponse{Version: version, FullRefetch: true, Changes: []Event{}}, http.StatusGone)
+		return
+	}
+
+	for i, change := range changes {
+		if change.Version > version {
+			changes = changes[:i]
+			break
+		}
+	}
+
+	resp := DeltaResponse{
+		Version: version,
+		Hash:    hash,
+		Changes: changes,
+	}
+	writeJSON(writer, resp, http.StatusOK)
+}
+
+func writeJSON(writer http.ResponseWriter, resp any, statusCode int) {
+	respBody, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	if _, err = writer.Write(respBody); err != nil {
+		slog.Error("Failed to respond", "response:", resp, "err:", err)
+	}
+}
@@ -0,0 +1,132 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_GetDelta_ReturnsChangesSinceVersion(t *testing.T) {
+	// given
+	store := NewStore()
+	handler := NewHandler(store)
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	appsResp := doAppsRequest(handler, "/apps")
+	var apps AppsResponse
+	if err := json.Unmarshal(appsResp.Body.Bytes(), &apps); err != nil {
+		t.Fatal(err)
+	}
+
+	store.Put("one", "a", Healthy)
+	if _, _, err := store.register("two", Instance{ID: "b", Host: "127.0.0.1:8081"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	store.Remove("one", "a")
+
+	deltaResp := doAppsRequest(handler, fmt.Sprintf("/apps/delta?since=%d", apps.Version))
+
+	// then
+	if appsResp.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v", appsResp.Code, http.StatusOK)
+	}
+	if apps.Hash != "UNKNOWN_1_" {
+		t.Fatalf("hash: got %s, want UNKNOWN_1_", apps.Hash)
+	}
+	if deltaResp.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v", deltaResp.Code, http.StatusOK)
+	}
+
+	var delta DeltaResponse
+	if err := json.Unmarshal(deltaResp.Body.Bytes(), &delta); err != nil {
+		t.Fatal(err)
+	}
+	wantTypes := []EventType{StatusChanged, Registered, Removed}
+	if len(delta.Changes) != len(wantTypes) {
+		t.Fatalf("len(changes) = %d, want %d", len(delta.Changes), len(wantTypes))
+	}
+	for i, wantType := range wantTypes {
+		if delta.Changes[i].Type != wantType {
+			t.Fatalf("changes[%d].Type = %s, want %s", i, delta.Changes[i].Type, wantType)
+		}
+		if delta.Changes[i].Version != apps.Version+uint64(i)+1 {
+			t.Fatalf("changes[%d].Version = %d, want %d", i, delta.Changes[i].Version, apps.Version+uint64(i)+1)
+		}
+	}
+	if delta.Version != apps.Version+3 {
+		t.Fatalf("version: got %d, want %d", delta.Version, apps.Version+3)
+	}
+	if delta.Hash != "UNKNOWN_1_" {
+		t.Fatalf("hash: got %s, want UNKNOWN_1_", delta.Hash)
+	}
+}
+
+func Test_GetDelta_TooOldVersion(t *testing.T) {
+	// given
+	store := newStore(1, make(map[string]map[string]Instance))
+	store.changes = newChangeLog(2)
+	handler := NewHandler(store)
+	for i := range 3 {
+		instance := Instance{ID: fmt.Sprintf("%d", i), Host: "127.0.0.1:8080"}
+		if _, _, err := store.register("one", instance, "", systemActor); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// when
+	resp := doAppsRequest(handler, "/apps/delta?since=0")
+
+	// then
+	if resp.Code != http.StatusGone {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusGone)
+	}
+	var delta DeltaResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &delta); err != nil {
+		t.Fatal(err)
+	}
+	if !delta.FullRefetch {
+		t.Fatalf("full refetch was not requested")
+	}
+}
+
+func doAppsRequest(handler http.Handler, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+	return recorder
+}
+
+func Test_GetDelta_HashFollowsChanges(t *testing.T) {
+	// given
+	store := NewStoreFrom(map[string]map[string]Instance{
+		"one": {"a": {ID: "a", Host: "127.0.0.1:8080", Status: Healthy}},
+	})
+	handler := NewHandler(store)
+	if _, _, err := store.register("two", Instance{ID: "b", Host: "127.0.0.1:8081"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	store.Put("two", "b", Healthy)
+	store.Put("one", "a", Down)
+	if _, _, err := store.register("two", Instance{ID: "c", Host: "127.0.0.1:8082"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	store.Remove("two", "b")
+
+	// when
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/apps/delta?since=0", nil))
+
+	// then
+	var delta DeltaResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &delta); err != nil {
+		t.Fatal(err)
+	}
+	if want := ReconcileHash(store.GetAll()); delta.Hash != want {
+		t.Fatalf("hash: got %q, want %q", delta.Hash, want)
+	}
+}
@@ -0,0 +1,201 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	IndexHeader   = "X-Eureka-Index"
+	defaultKVWait = time.Minute
+	maxWait       = 10 * time.Minute
+	waitSlack     = 10 * time.Second
+)
+
+type RemoteKV interface {
+	LookupKV(region string, prefix string) ([]KVEntry, bool)
+}
+
+type GetKVHandler struct {
+	kv     *KV
+	region string
+	remote RemoteKV
+}
+
+func (h GetKVHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	key := request.PathValue("key")
+	query := request.URL.Query()
+	recurse := query.Has("recurse")
+
+	if region := query.Get("region"); region != "" && region != h.region {
+		h.serveRemote(writer, region, key, recurse)
+		return
+	}
+
+	if query.Has("index") {
+		index, err := strconv.ParseUint(query.Get("index"), 10, 64)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		wait, err := parseWait(query.Get("wait"), defaultKVWait)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = extendWriteDeadline(writer, wait); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(request.Context(), wait)
+		defer cancel()
+		h.kv.Wait(ctx, key, recurse, index)
+	}
+
+	if recurse {
+		entries, index := h.kv.List(key)
+		writer.Header().Set(IndexHeader, strconv.FormatUint(index, 10))
+		writeJSON(writer, KVResponse{Index: index, Entries: entries}, http.StatusOK)
+		return
+	}
+
+	entry, index, ok := h.kv.Get(key)
+	writer.Header().Set(IndexHeader, strconv.FormatUint(index, 10))
+	if !ok {
+		http.Error(writer, fmt.Sprintf("key %q not found", key), http.StatusNotFound)
+		return
+	}
+	writeJSON(writer, entry, http.StatusOK)
+}
+
+func (h GetKVHandler) serveRemote(writer http.ResponseWriter, region string, key string, recurse bool) {
+	if h.remote == nil {
+		http.Error(writer, fmt.Sprintf("unknown region %q", region), http.StatusNotFound)
+		return
+	}
+
+	entries, ok := h.remote.LookupKV(region, key)
+	if !ok {
+		http.Error(writer, fmt.Sprintf("unknown region %q", region), http.StatusNotFound)
+		return
+	}
+
+	if recurse {
+		writeJSON(writer, KVResponse{Entries: entries, Region: region}, http.StatusOK)
+		return
+	}
+	for _, entry := range entries {
+		if entry.Key == key {
+			writeJSON(writer, entry, http.StatusOK)
+			return
+		}
+	}
+	http.Error(writer, fmt.Sprintf("key %q not found in region %q", key, region), http.StatusNotFound)
+}
+
+type PutKVHandler struct {
+	store *Store
+}
+
+func (h PutKVHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	cas, err := parseCAS(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	value, err := io.ReadAll(io.LimitReader(request.Body, maxValueSize+1))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	entry, err := h.store.KV().Put(request.PathValue("key"), string(value), cas)
+	if err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+	h.store.auditKV(AuditKVPut, entry.Key, callerActor(request))
+
+	writer.Header().Set(IndexHeader, strconv.FormatUint(entry.ModifyIndex, 10))
+	writeJSON(writer, entry, http.StatusOK)
+}
+
+type DeleteKVHandler struct {
+	store *Store
+}
+
+func (h DeleteKVHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	key := request.PathValue("key")
+
+	cas, err := parseCAS(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.URL.Query().Has("recurse") {
+		if cas != nil {
+			http.Error(writer, "cas is not supported together with recurse", http.StatusBadRequest)
+			return
+		}
+		if key == "" {
+			http.Error(writer, "recurse needs a key prefix", http.StatusBadRequest)
+			return
+		}
+		h.store.KV().DeleteTree(key)
+		h.store.auditKV(AuditKVDeleteTree, key, callerActor(request))
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err = h.store.KV().Delete(key, cas); err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+	h.store.auditKV(AuditKVDelete, key, callerActor(request))
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func parseCAS(request *http.Request) (*uint64, error) {
+	query := request.URL.Query()
+	if !query.Has("cas") {
+		return nil, nil
+	}
+	cas, err := strconv.ParseUint(query.Get("cas"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cas %q: %w", query.Get("cas"), err)
+	}
+	return &cas, nil
+}
+
+func parseWait(rawWait string, fallback time.Duration) (time.Duration, error) {
+	if rawWait == "" {
+		return fallback, nil
+	}
+	wait, err := time.ParseDuration(rawWait)
+	if err != nil {
+		return 0, err
+	}
+	if wait < 0 {
+		return 0, fmt.Errorf("wait %q must not be negative", rawWait)
+	}
+	return min(wait, maxWait), nil
+}
+
+// extendWriteDeadline lets a blocking request outlive the server's write
+// timeout by as long as it may wait.
+func extendWriteDeadline(writer http.ResponseWriter, wait time.Duration) error {
+	controller := http.NewResponseController(writer)
+	if err := controller.SetWriteDeadline(time.Now().Add(wait + waitSlack)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+type GetLocksHandler struct {
+	store *Store
+}
+
+func (h GetLocksHandler) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	writeJSON(writer, LocksResponse{Locks: h.store.Locks()}, http.StatusOK)
+}
+
+type GetLockHandler struct {
+	store *Store
+}
+
+func (h GetLockHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	name := request.PathValue("name")
+
+	lock, ok := h.store.Lock(name)
+	if !ok {
+		http.Error(writer, fmt.Sprintf("lock %q is not held", name), http.StatusNotFound)
+		return
+	}
+	writeJSON(writer, lock, http.StatusOK)
+}
+
+type AcquireLockHandler struct {
+	store *Store
+}
+
+func (h AcquireLockHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	lockReq, ok := decodeLockRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	wait, err := parseWait(request.URL.Query().Get("wait"), 0)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = extendWriteDeadline(writer, wait); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), wait)
+	defer cancel()
+
+	lock, err := h.store.acquireLock(ctx, request.PathValue("name"), lockReq.ServiceID, lockReq.InstanceID)
+	if err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+	writeJSON(writer, lock, http.StatusOK)
+}
+
+type ReleaseLockHandler struct {
+	store *Store
+}
+
+func (h ReleaseLockHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	lockReq, ok := decodeLockRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := h.store.releaseLock(request.PathValue("name"), lockReq.ServiceID, lockReq.InstanceID); err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func decodeLockRequest(writer http.ResponseWriter, request *http.Request) (LockRequest, bool) {
+	var lockReq LockRequest
+	if err := json.NewDecoder(request.Body).Decode(&lockReq); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return LockRequest{}, false
+	}
+	if lockReq.ServiceID == "" || lockReq.InstanceID == "" {
+		http.Error(writer, "service_id and instance_id are required", http.StatusBadRequest)
+		return LockRequest{}, false
+	}
+	return lockReq, true
+}
@@ -0,0 +1,114 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeRemoteCatalog map[string]map[string][]HostStatus
+
+func (f fakeRemoteCatalog) Regions() []string {
+	regions := make([]string, 0, len(f))
+	for region := range f {
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+func (f fakeRemoteCatalog) Lookup(region string, serviceID string) ([]HostStatus, bool) {
+	services, ok := f[region]
+	if !ok {
+		return nil, false
+	}
+	return services[serviceID], true
+}
+
+func Test_GetHostStatuses_RemoteRegion(t *testing.T) {
+	// given
+	store := NewStore()
+	remote := fakeRemoteCatalog{
+		"us": {"one": {{InstanceID: "b", Host: "10.0.0.2:8080", Status: Healthy, Health: Healthy}}},
+	}
+	handler := NewHandler(store, WithRegion("eu"), WithRemoteCatalog(remote))
+
+	// when
+	resp := doAppsRequest(handler, "/service-id/one?region=us")
+
+	// then
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusOK)
+	}
+	got := decodeHostStatusesResponse(t, resp)
+	if got.Region != "us" || len(got.HostStatuses) != 1 || got.HostStatuses[0].InstanceID != "b" {
+		t.Fatalf("got %v, want the instance from region us", got)
+	}
+}
+
+func Test_GetHostStatuses_UnknownRegion(t *testing.T) {
+	// given
+	handler := NewHandler(NewStore(), WithRegion("eu"), WithRemoteCatalog(fakeRemoteCatalog{}))
+
+	// when
+	resp := doAppsRequest(handler, "/service-id/one?region=us")
+
+	// then
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusNotFound)
+	}
+}
+
+func Test_GetHostStatuses_FallbackRemote(t *testing.T) {
+	// given
+	store := NewStore()
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	store.Put("one", "a", Down)
+	remote := fakeRemoteCatalog{
+		"us": {"one": {{InstanceID: "b", Host: "10.0.0.2:8080", Status: Healthy, Health: Healthy}}},
+	}
+	handler := NewHandler(store, WithRegion("eu"), WithRemoteCatalog(remote))
+
+	// when
+	withoutFallback := decodeHostStatusesResponse(t, doAppsRequest(handler, "/service-id/one"))
+	withFallback := decodeHostStatusesResponse(t, doAppsRequest(handler, "/service-id/one?fallback=remote"))
+
+	// then
+	if withoutFallback.Region != "" || withoutFallback.HostStatuses[0].InstanceID != "a" {
+		t.Fatalf("without fallback: got %v, want the local instance", withoutFallback)
+	}
+	if withFallback.Region != "us" || withFallback.HostStatuses[0].InstanceID != "b" {
+		t.Fatalf("with fallback: got %v, want the instance from region us", withFallback)
+	}
+}
+
+func Test_GetHostStatuses_FallbackPrefersHealthyLocal(t *testing.T) {
+	// given
+	store := NewStore()
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	store.Put("one", "a", Healthy)
+	remote := fakeRemoteCatalog{
+		"us": {"one": {{InstanceID: "b", Host: "10.0.0.2:8080", Status: Healthy, Health: Healthy}}},
+	}
+	handler := NewHandler(store, WithRegion("eu"), WithRemoteCatalog(remote))
+
+	// when
+	got := decodeHostStatusesResponse(t, doAppsRequest(handler, "/service-id/one?fallback=remote"))
+
+	// then
+	if got.Region != "" || got.HostStatuses[0].InstanceID != "a" {
+		t.Fatalf("got %v, want the local instance", got)
+	}
+}
+
+func decodeHostStatusesResponse(t *testing.T, resp *httptest.ResponseRecorder) GetHostStatusesResponse {
+	var got GetHostStatusesResponse
+	if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
@@ -11,6 +11,9 @@ import (
 )
 
 func Test_RegisterHost_SingleServiceTwoHosts(t *testing.T) {
+	// clean up
+	cleanUp()
+
 	// given
 	registerURL := "/service-id/register"
 
@@ -33,10 +36,10 @@ func Test_RegisterHost_SingleServiceTwoHosts(t *testing.T) {
 		t.Fatalf("status code: got %v, want %v", respTwo.Code, http.StatusCreated)
 	}
 
-	if len(serviceIDToHostStatuses) != 1 {
-		t.Fatalf("len(serviceIDToHostStatuses) != %d", len(serviceIDToHostStatuses))
+	if len(serviceIDToInstances()) != 1 {
+		t.Fatalf("len(serviceIDToInstances()) != %d", len(serviceIDToInstances()))
 	}
-	hosts, ok := serviceIDToHostStatuses[serviceID]
+	hosts, ok := serviceIDToInstances()[serviceID]
 	if !ok {
 		t.Fatalf("serviceID: %s not registered", serviceID)
 	}
@@ -45,12 +48,12 @@ func Test_RegisterHost_SingleServiceTwoHosts(t *testing.T) {
 		t.Fatalf("len(hosts) = %d, want 2", len(hosts))
 	}
 	for _, actual := range []string{hostOne, hostTwo} {
-		status, ok := hosts[actual]
+		instance, ok := hosts[actual]
 		if !ok {
 			t.Fatalf("hosts[%s] not found", actual)
 		}
-		if status != Unknown {
-			t.Fatalf("hosts[%s] = %s, want Unknown", actual, status)
+		if instance.Status != Unknown {
+			t.Fatalf("hosts[%s] = %s, want Unknown", actual, instance.Status)
 		}
 	}
 }
@@ -82,10 +85,10 @@ func Test_RegisterHost_TwoServicesTwoHosts(t *testing.T) {
 		t.Fatalf("status code: got %v, want %v", respTwo.Code, http.StatusCreated)
 	}
 
-	if len(serviceIDToHostStatuses) != 2 {
-		t.Fatalf("len(serviceIDToHostStatuses) != %d", len(serviceIDToHostStatuses))
+	if len(serviceIDToInstances()) != 2 {
+		t.Fatalf("len(serviceIDToInstances()) != %d", len(serviceIDToInstances()))
 	}
-	hosts, ok := serviceIDToHostStatuses[serviceIDOne]
+	hosts, ok := serviceIDToInstances()[serviceIDOne]
 	if !ok {
 		t.Fatalf("serviceID: %s not registered", serviceIDOne)
 	}
@@ -93,24 +96,24 @@ func Test_RegisterHost_TwoServicesTwoHosts(t *testing.T) {
 	if len(hosts) != 1 {
 		t.Fatalf("len(hosts) = %d, want 1", len(hosts))
 	}
-	status, ok := hosts[hostOne]
+	instance, ok := hosts[hostOne]
 	if !ok {
 		t.Fatalf("hosts[%s] not found", hostOne)
 	}
-	if status != Unknown {
-		t.Fatalf("hosts[%s] = %s, want Unknown", hostOne, status)
+	if instance.Status != Unknown {
+		t.Fatalf("hosts[%s] = %s, want Unknown", hostOne, instance.Status)
 	}
 
-	hosts, ok = serviceIDToHostStatuses[serviceIDTwo]
+	hosts, ok = serviceIDToInstances()[serviceIDTwo]
 	if !ok {
 		t.Fatalf("serviceID: %s not registered", serviceIDTwo)
 	}
-	status, ok = hosts[hostTwo]
+	instance, ok = hosts[hostTwo]
 	if !ok {
 		t.Fatalf("hosts[%s] not found", hostTwo)
 	}
-	if status != Unknown {
-		t.Fatalf("hosts[%s] = %s, want Unknown", hostTwo, status)
+	if instance.Status != Unknown {
+		t.Fatalf("hosts[%s] = %s, want Unknown", hostTwo, instance.Status)
 	}
 }
 
@@ -174,7 +177,7 @@ func Test_RemoveHost_RemoveNotAll(t *testing.T) {
 		t.Fatalf("status code: got %v, want %v", respThree.Code, http.StatusOK)
 	}
 
-	hosts, ok := serviceIDToHostStatuses[serviceID]
+	hosts, ok := serviceIDToInstances()[serviceID]
 	if !ok {
 		t.Fatalf("serviceID: %s not registered", serviceID)
 	}
@@ -182,17 +185,17 @@ func Test_RemoveHost_RemoveNotAll(t *testing.T) {
 	if len(hosts) != 1 {
 		t.Fatalf("len(hosts) = %d, want 1", len(hosts))
 	}
-	status, ok := hosts[hostOne]
+	instance, ok := hosts[hostOne]
 	if ok {
-		t.Fatalf("hosts[%s] = %v, want nil", hostOne, status)
+		t.Fatalf("hosts[%s] = %v, want nil", hostOne, instance)
 	}
 
-	status, ok = hosts[hostTwo]
+	instance, ok = hosts[hostTwo]
 	if !ok {
 		t.Fatalf("hosts[%s] not found", hostTwo)
 	}
-	if status != Unknown {
-		t.Fatalf("hosts[%s] = %s, want Unknown", hostOne, status)
+	if instance.Status != Unknown {
+		t.Fatalf("hosts[%s] = %s, want Unknown", hostOne, instance.Status)
 	}
 }
 
@@ -222,7 +225,7 @@ func Test_RemoveHost_RemoveAll(t *testing.T) {
 		t.Fatalf("status code: got %v, want %v", respTwo.Code, http.StatusOK)
 	}
 
-	_, ok := serviceIDToHostStatuses[serviceID]
+	_, ok := serviceIDToInstances()[serviceID]
 	if ok {
 		t.Fatalf("serviceID: %s is registered", serviceID)
 	}
@@ -247,12 +250,42 @@ func Test_RemoveHost_RemoveNotExisting(t *testing.T) {
 		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusOK)
 	}
 
-	_, ok := serviceIDToHostStatuses[serviceID]
+	_, ok := serviceIDToInstances()[serviceID]
 	if ok {
 		t.Fatalf("serviceID: %s is registered", serviceID)
 	}
 }
 
+func Test_RemoveHost_V1ResponseUnchanged(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	registerURL := "/service-id/register"
+	removeURL := "/service-id/remove"
+
+	serviceID := "v1"
+	host := "127.0.0.1:8080"
+	doRequest(t, http.MethodPost, registerURL, RegisterHostRequest{ServiceID: serviceID, Host: host})
+
+	// when
+	existing := doRequest(t, http.MethodPost, removeURL, RemoveHostRequest{ServiceID: serviceID, Host: host})
+	missing := doRequest(t, http.MethodPost, removeURL, RemoveHostRequest{ServiceID: serviceID, Host: host})
+
+	// then
+	for _, resp := range []*httptest.ResponseRecorder{existing, missing} {
+		if resp.Code != http.StatusOK {
+			t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusOK)
+		}
+		if resp.Body.Len() != 0 {
+			t.Fatalf("body: got %q, want empty", resp.Body.String())
+		}
+		if contentType := resp.Header().Get("Content-Type"); contentType != "" {
+			t.Fatalf("content type: got %q, want none", contentType)
+		}
+	}
+}
+
 func Test_GetHostStatuses_EmptyResponse(t *testing.T) {
 	// given
 	getURL := "/service-id/not-exist"
@@ -311,12 +344,18 @@ func Test_GetHostStatuses_NormalResponse(t *testing.T) {
 	want := GetHostStatusesResponse{
 		HostStatuses: []HostStatus{
 			{
-				Host:   hostOne,
-				Status: Unknown,
+				InstanceID: hostOne,
+				Host:       hostOne,
+				Status:     Unknown,
+				Health:     Unknown,
+				Weight:     DefaultWeight,
 			},
 			{
-				Host:   hostTwo,
-				Status: Unknown,
+				InstanceID: hostTwo,
+				Host:       hostTwo,
+				Status:     Unknown,
+				Health:     Unknown,
+				Weight:     DefaultWeight,
 			},
 		},
 	}
@@ -360,14 +399,25 @@ func doBrokenRequest(method string, target string) *httptest.ResponseRecorder {
 }
 
 func cleanUp() {
-	for key := range serviceIDToHostStatuses {
-		delete(serviceIDToHostStatuses, key)
+	if err := testStore.Restore(Snapshot{Version: SnapshotVersion}, Replace); err != nil {
+		panic(err)
+	}
+}
+
+func serviceIDToInstances() map[string]map[string]Instance {
+	result := make(map[string]map[string]Instance)
+	for serviceID, instances := range testStore.GetServiceIDsToInstances() {
+		result[serviceID] = make(map[string]Instance, len(instances))
+		for _, instance := range instances {
+			result[serviceID][instance.ID] = instance
+		}
 	}
+	return result
 }
 
 var (
-	serviceIDToHostStatuses = make(map[string]map[string]Status)
-	handler                 = NewHandler(NewStoreFrom(serviceIDToHostStatuses))
-	buffer                  = bytes.NewBuffer(make([]byte, 0, 1024))
-	encoder                 = json.NewEncoder(buffer)
+	testStore = NewStore()
+	handler   = NewHandler(testStore)
+	buffer    = bytes.NewBuffer(make([]byte, 0, 1024))
+	encoder   = json.NewEncoder(buffer)
 )
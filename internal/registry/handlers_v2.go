@@ -0,0 +1,338 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+type PutInstanceHandler struct {
+	store *Store
+}
+
+func (h PutInstanceHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+	instanceID := request.PathValue("instanceID")
+
+	var regReq RegisterInstanceRequest
+	if err := json.NewDecoder(request.Body).Decode(&regReq); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	host := regReq.Host
+	if host == "" {
+		host = instanceID
+	}
+
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hostStatus, created, err := h.store.register(serviceID, regReq.instance(instanceID, host), request.Header.Get("If-Match"), callerActor(request))
+	if err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+
+	statusCode := http.StatusOK
+	if created {
+		statusCode = http.StatusCreated
+	}
+	writeHostStatus(writer, hostStatus, statusCode)
+}
+
+type PostInstanceHandler struct {
+	store *Store
+}
+
+func (h PostInstanceHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+
+	var regReq RegisterInstanceRequest
+	if err := json.NewDecoder(request.Body).Decode(&regReq); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if _, _, err := net.SplitHostPort(regReq.Host); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	instanceID := regReq.InstanceID
+	if instanceID == "" {
+		instanceID = newInstanceID()
+	}
+
+	hostStatus, created, err := h.store.register(serviceID, regReq.instance(instanceID, regReq.Host), "", callerActor(request))
+	if err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+
+	statusCode := http.StatusOK
+	if created {
+		statusCode = http.StatusCreated
+	}
+	writer.Header().Set("Location", fmt.Sprintf("/v2/services/%s/instances/%s", serviceID, instanceID))
+	writeHostStatus(writer, hostStatus, statusCode)
+}
+
+type PutHealthHandler struct {
+	store *Store
+}
+
+func (h PutHealthHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+	instanceID := request.PathValue("instanceID")
+
+	var check Check
+	if err := json.NewDecoder(request.Body).Decode(&check); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !check.Status.valid() {
+		writeStoreError(writer, ErrInvalidStatus)
+		return
+	}
+	if check.CheckedAt.IsZero() {
+		check.CheckedAt = h.store.now()
+	}
+
+	hostStatus, err := h.store.reportCheck(serviceID, instanceID, check, true, callerActor(request))
+	if err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+
+	writeHostStatus(writer, hostStatus, http.StatusOK)
+}
+
+type ReportCallHandler struct {
+	detector *OutlierDetector
+}
+
+func (h ReportCallHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+
+	var reportReq ReportCallRequest
+	if err := json.NewDecoder(request.Body).Decode(&reportReq); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	switch {
+	case reportReq.InstanceID != "":
+		err = h.detector.Report(serviceID, reportReq.InstanceID, reportReq.ErrorClass, reportReq.Count)
+	case reportReq.Host != "":
+		err = h.detector.ReportHost(serviceID, reportReq.Host, reportReq.ErrorClass, reportReq.Count)
+	default:
+		http.Error(writer, "instance_id or host is required", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+type DrainHandler struct {
+	drainer Drainer
+}
+
+func (h DrainHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+	instanceID := request.PathValue("instanceID")
+
+	var drainReq DrainRequest
+	if err := json.NewDecoder(request.Body).Decode(&drainReq); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var period time.Duration
+	if drainReq.Period != "" {
+		parsed, err := time.ParseDuration(drainReq.Period)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		period = parsed
+	}
+
+	hostStatus, err := h.drainer.Drain(serviceID, instanceID, period, callerActor(request))
+	if err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+
+	writeHostStatus(writer, hostStatus, http.StatusOK)
+}
+
+type CancelDrainHandler struct {
+	drainer Drainer
+}
+
+func (h CancelDrainHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+	instanceID := request.PathValue("instanceID")
+
+	hostStatus, err := h.drainer.Cancel(serviceID, instanceID, callerActor(request))
+	if err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+
+	writeHostStatus(writer, hostStatus, http.StatusOK)
+}
+
+type DeleteInstanceHandler struct {
+	store *Store
+}
+
+func (h DeleteInstanceHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+	instanceID := request.PathValue("instanceID")
+
+	if err := h.store.removeIfMatch(serviceID, instanceID, request.Header.Get("If-Match"), callerActor(request)); err != nil {
+		writeStoreError(writer, err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+type GetInstanceHandler struct {
+	store *Store
+}
+
+func (h GetInstanceHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.PathValue("serviceID")
+	instanceID := request.PathValue("instanceID")
+
+	hostStatus, ok := h.store.Find(serviceID, instanceID)
+	if !ok {
+		writeStoreError(writer, ErrNotFound)
+		return
+	}
+
+	writeHostStatus(writer, hostStatus, http.StatusOK)
+}
+
+type GetServicesHandler struct {
+	store *Store
+}
+
+func (h GetServicesHandler) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	resp := GetServicesResponse{Services: h.store.GetAll()}
+	respBody, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	if _, err = writer.Write(respBody); err != nil {
+		slog.Error("Failed to respond", "response:", resp, "err:", err)
+	}
+}
+
+type EventsHandler struct {
+	store *Store
+}
+
+func (h EventsHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	serviceID := request.URL.Query().Get("service")
+
+	controller := http.NewResponseController(writer)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	events, cancel := h.store.Subscribe(eventsBufferSize, nil)
+	defer cancel()
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		slog.Error("Failed to flush event stream", "err:", err)
+		return
+	}
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case event := <-events:
+			if serviceID != "" && event.ServiceID != serviceID {
+				continue
+			}
+			if err := writeEvent(writer, event); err != nil {
+				slog.Error("Failed to write event", "event:", event, "err:", err)
+				return
+			}
+			if err := controller.Flush(); err != nil {
+				slog.Error("Failed to flush event stream", "err:", err)
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(writer io.Writer, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+const eventsBufferSize = 64
+
+func writeHostStatus(writer http.ResponseWriter, hostStatus HostStatus, statusCode int) {
+	respBody, err := json.Marshal(hostStatus)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("ETag", hostStatus.ETag())
+	writer.WriteHeader(statusCode)
+	if _, err = writer.Write(respBody); err != nil {
+		slog.Error("Failed to respond", "response:", hostStatus, "err:", err)
+	}
+}
+
+func writeStoreError(writer http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(writer, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrPreconditionFailed):
+		http.Error(writer, err.Error(), http.StatusPreconditionFailed)
+	case errors.Is(err, ErrInvalidStatus), errors.Is(err, ErrInvalidErrorClass), errors.Is(err, ErrInvalidDrainPeriod),
+		errors.Is(err, ErrInvalidWeight), errors.Is(err, ErrInvalidKey), errors.Is(err, ErrInvalidLockName):
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrLockHeld), errors.Is(err, ErrLockHolderDown):
+		http.Error(writer, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrValueTooLarge):
+		http.Error(writer, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, ErrLimitExceeded):
+		http.Error(writer, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,309 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func Test_PutInstance_CreateThenUpdate(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	serviceID := "one"
+	host := "127.0.0.1:8080"
+	instanceURL := fmt.Sprintf("/v2/services/%s/instances/%s", serviceID, host)
+
+	// when
+	respOne := doHeaderRequest(http.MethodPut, instanceURL, nil)
+	respTwo := doHeaderRequest(http.MethodPut, instanceURL, nil)
+
+	// then
+	if respOne.Code != http.StatusCreated {
+		t.Fatalf("status code: got %v, want %v", respOne.Code, http.StatusCreated)
+	}
+	if respTwo.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v", respTwo.Code, http.StatusOK)
+	}
+	if respOne.Header().Get("ETag") == "" {
+		t.Fatalf("ETag header is missing")
+	}
+
+	var got HostStatus
+	if err := json.Unmarshal(respTwo.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := HostStatus{InstanceID: host, Host: host, Status: Unknown, Health: Unknown, Weight: DefaultWeight}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if len(serviceIDToInstances()[serviceID]) != 1 {
+		t.Fatalf("len(hosts) = %d, want 1", len(serviceIDToInstances()[serviceID]))
+	}
+}
+
+func Test_PutInstance_InvalidHost(t *testing.T) {
+	// given
+	instanceURL := "/v2/services/one/instances/wrong-host"
+
+	// when
+	resp := doHeaderRequest(http.MethodPut, instanceURL, nil)
+
+	// then
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusBadRequest)
+	}
+}
+
+func Test_GetInstance_NotFound(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	instanceURL := "/v2/services/one/instances/127.0.0.1:8080"
+
+	// when
+	resp := doHeaderRequest(http.MethodGet, instanceURL, nil)
+
+	// then
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusNotFound)
+	}
+}
+
+func Test_DeleteInstance_NotFound(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	instanceURL := "/v2/services/one/instances/127.0.0.1:8080"
+
+	// when
+	resp := doHeaderRequest(http.MethodDelete, instanceURL, nil)
+
+	// then
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusNotFound)
+	}
+}
+
+func Test_DeleteInstance_IfMatch(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	serviceID := "one"
+	host := "127.0.0.1:8080"
+	instanceURL := fmt.Sprintf("/v2/services/%s/instances/%s", serviceID, host)
+
+	// when
+	respPut := doHeaderRequest(http.MethodPut, instanceURL, nil)
+	etag := respPut.Header().Get("ETag")
+
+	respStale := doHeaderRequest(http.MethodDelete, instanceURL, map[string]string{"If-Match": `"stale"`})
+	respDelete := doHeaderRequest(http.MethodDelete, instanceURL, map[string]string{"If-Match": etag})
+	respGet := doHeaderRequest(http.MethodGet, instanceURL, nil)
+
+	// then
+	if respStale.Code != http.StatusPreconditionFailed {
+		t.Fatalf("status code: got %v, want %v", respStale.Code, http.StatusPreconditionFailed)
+	}
+	if respDelete.Code != http.StatusNoContent {
+		t.Fatalf("status code: got %v, want %v", respDelete.Code, http.StatusNoContent)
+	}
+	if respGet.Code != http.StatusNotFound {
+		t.Fatalf("status code: got %v, want %v", respGet.Code, http.StatusNotFound)
+	}
+}
+
+func Test_PutInstance_IfMatchAfterStatusChange(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	serviceID := "one"
+	host := "127.0.0.1:8080"
+	instanceURL := fmt.Sprintf("/v2/services/%s/instances/%s", serviceID, host)
+
+	// when
+	respPut := doHeaderRequest(http.MethodPut, instanceURL, nil)
+	etag := respPut.Header().Get("ETag")
+
+	testStore.Put(serviceID, host, Healthy)
+
+	respStale := doHeaderRequest(http.MethodPut, instanceURL, map[string]string{"If-Match": etag})
+
+	// then
+	if respStale.Code != http.StatusPreconditionFailed {
+		t.Fatalf("status code: got %v, want %v", respStale.Code, http.StatusPreconditionFailed)
+	}
+}
+
+func Test_PutInstance_NewAddressUpdatesInPlace(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	serviceID := "one"
+	instanceID := "instance-one"
+	hostOne := "127.0.0.1:8080"
+	hostTwo := "127.0.0.1:8081"
+	instanceURL := fmt.Sprintf("/v2/services/%s/instances/%s", serviceID, instanceID)
+
+	// when
+	respOne := doRequest(t, http.MethodPut, instanceURL, RegisterInstanceRequest{Host: hostOne})
+	respTwo := doRequest(t, http.MethodPut, instanceURL, RegisterInstanceRequest{Host: hostTwo})
+
+	// then
+	if respOne.Code != http.StatusCreated {
+		t.Fatalf("status code: got %v, want %v", respOne.Code, http.StatusCreated)
+	}
+	if respTwo.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v", respTwo.Code, http.StatusOK)
+	}
+
+	instances := serviceIDToInstances()[serviceID]
+	if len(instances) != 1 {
+		t.Fatalf("len(instances) = %d, want 1", len(instances))
+	}
+	if instances[instanceID].Host != hostTwo {
+		t.Fatalf("instances[%s].Host = %s, want %s", instanceID, instances[instanceID].Host, hostTwo)
+	}
+}
+
+func Test_RegisterHost_TwoInstancesSameHost(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	registerURL := "/service-id/register"
+
+	serviceID := "one"
+	host := "127.0.0.1:8080"
+
+	// when
+	regReq := RegisterHostRequest{ServiceID: serviceID, InstanceID: "a", Host: host}
+	respOne := doRequest(t, http.MethodPost, registerURL, regReq)
+
+	regReq = RegisterHostRequest{ServiceID: serviceID, InstanceID: "b", Host: host}
+	respTwo := doRequest(t, http.MethodPost, registerURL, regReq)
+
+	// then
+	if respOne.Code != http.StatusCreated {
+		t.Fatalf("status code: got %v, want %v", respOne.Code, http.StatusCreated)
+	}
+	if respTwo.Code != http.StatusCreated {
+		t.Fatalf("status code: got %v, want %v", respTwo.Code, http.StatusCreated)
+	}
+	if len(serviceIDToInstances()[serviceID]) != 2 {
+		t.Fatalf("len(instances) = %d, want 2", len(serviceIDToInstances()[serviceID]))
+	}
+}
+
+func Test_PostInstance_GeneratesID(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	serviceID := "one"
+	host := "127.0.0.1:8080"
+	instancesURL := fmt.Sprintf("/v2/services/%s/instances", serviceID)
+
+	// when
+	resp := doRequest(t, http.MethodPost, instancesURL, RegisterInstanceRequest{Host: host})
+
+	// then
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusCreated)
+	}
+
+	var got HostStatus
+	if err := json.Unmarshal(resp.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.InstanceID == "" || got.InstanceID == host {
+		t.Fatalf("instance ID %q was not generated", got.InstanceID)
+	}
+	wantLocation := fmt.Sprintf("%s/%s", instancesURL, got.InstanceID)
+	if resp.Header().Get("Location") != wantLocation {
+		t.Fatalf("Location: got %s, want %s", resp.Header().Get("Location"), wantLocation)
+	}
+}
+
+func doHeaderRequest(method string, target string, headers map[string]string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, nil)
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func Test_PutHealth_UpdatesStatus(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	serviceID := "one"
+	host := "127.0.0.1:8080"
+	instanceURL := fmt.Sprintf("/v2/services/%s/instances/%s", serviceID, host)
+	doRequest(t, http.MethodPut, instanceURL, RegisterInstanceRequest{Host: host, ReportsHealth: true})
+
+	// when
+	resp := doRequest(t, http.MethodPut, instanceURL+"/health", Check{Status: Healthy})
+
+	// then
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusOK)
+	}
+
+	got, _ := testStore.Find(serviceID, host)
+	if got.Status != Healthy {
+		t.Fatalf("status: got %v, want %v", got.Status, Healthy)
+	}
+	if !got.ReportsHealth {
+		t.Fatalf("reports health: got %v, want %v", got.ReportsHealth, true)
+	}
+}
+
+func Test_PutHealth_NotFound(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	instanceURL := "/v2/services/one/instances/127.0.0.1:8080/health"
+
+	// when
+	resp := doRequest(t, http.MethodPut, instanceURL, Check{Status: Healthy})
+
+	// then
+	if resp.Code != http.StatusNotFound {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusNotFound)
+	}
+}
+
+func Test_PutHealth_InvalidStatus(t *testing.T) {
+	// clean up
+	cleanUp()
+
+	// given
+	serviceID := "one"
+	host := "127.0.0.1:8080"
+	instanceURL := fmt.Sprintf("/v2/services/%s/instances/%s", serviceID, host)
+	doRequest(t, http.MethodPut, instanceURL, RegisterInstanceRequest{Host: host, ReportsHealth: true})
+
+	// when
+	resp := doRequest(t, http.MethodPut, instanceURL+"/health", Check{Status: "wrong"})
+
+	// then
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusBadRequest)
+	}
+}
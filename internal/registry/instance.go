@@ -0,0 +1,59 @@
+package registry
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+type Instance struct {
+	ID        string            `json:"id"`
+	Host      string            `json:"host"`
+	Zone      string            `json:"zone,omitempty"`
+	Status    Status            `json:"status"`
+	Override  *Override         `json:"override,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	LastCheck *Check            `json:"last_check,omitempty"`
+	Ejection  *Ejection         `json:"ejection,omitempty"`
+	Drain     *Drain            `json:"drain,omitempty"`
+	Weight    *int              `json:"weight,omitempty"`
+
+	ReportsHealth bool      `json:"reports_health,omitempty"`
+	ReportedAt    time.Time `json:"reported_at,omitzero"`
+}
+
+func (i Instance) hostStatus(now time.Time) HostStatus {
+	hostStatus := HostStatus{
+		InstanceID: i.ID,
+		Host:       i.Host,
+		Zone:       i.Zone,
+		Status:     i.Status,
+		Health:     i.Status,
+		Metadata:   i.Metadata,
+		LastCheck:  i.LastCheck,
+		Weight:     i.weight(),
+
+		ReportsHealth: i.ReportsHealth,
+	}
+	if i.Ejection.active(now) {
+		hostStatus.Status = Down
+		hostStatus.Ejection = i.Ejection
+	}
+	if i.Drain != nil {
+		hostStatus.Status = Draining
+		hostStatus.Drain = i.Drain
+	}
+	if i.Override.active(now) {
+		hostStatus.Status = i.Override.Status
+		hostStatus.Override = i.Override
+	}
+	return hostStatus
+}
+
+func newInstanceID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,245 @@
+package registry
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidKey    = errors.New("invalid key")
+	ErrValueTooLarge = errors.New("value too large")
+)
+
+const (
+	maxKeyLength = 512
+	maxValueSize = 512 << 10
+)
+
+type KVEntry struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	CreateIndex uint64 `json:"create_index"`
+	ModifyIndex uint64 `json:"modify_index"`
+}
+
+// KV is a hierarchical key/value store for shared dynamic configuration. Keys
+// are slash separated paths, every write bumps one store-wide index, which is
+// what compare-and-swap and blocking reads are based on.
+type KV struct {
+	entries map[string]KVEntry
+	index   uint64
+	waiters map[*kvWaiter]struct{}
+	lock    sync.RWMutex
+}
+
+type kvWaiter struct {
+	key     string
+	recurse bool
+	changed chan struct{}
+}
+
+func (w *kvWaiter) watches(key string) bool {
+	if w.recurse {
+		return strings.HasPrefix(key, w.key)
+	}
+	return key == w.key
+}
+
+func checkKey(key string) error {
+	if key == "" || len(key) > maxKeyLength || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
+
+func (kv *KV) Get(key string) (KVEntry, uint64, bool) {
+	kv.lock.RLock()
+	defer kv.lock.RUnlock()
+
+	entry, ok := kv.entries[key]
+	return entry, kv.index, ok
+}
+
+// List returns the entries whose key starts with prefix, ordered by key.
+func (kv *KV) List(prefix string) ([]KVEntry, uint64) {
+	kv.lock.RLock()
+	defer kv.lock.RUnlock()
+
+	return kv.list(prefix), kv.index
+}
+
+func (kv *KV) list(prefix string) []KVEntry {
+	entries := make([]KVEntry, 0)
+	for key, entry := range kv.entries {
+		if strings.HasPrefix(key, prefix) {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b KVEntry) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return entries
+}
+
+// Put writes value under key. A non-nil cas makes the write conditional: zero
+// only creates a missing key, any other value must equal the key's modify index.
+func (kv *KV) Put(key string, value string, cas *uint64) (KVEntry, error) {
+	if err := checkKey(key); err != nil {
+		return KVEntry{}, err
+	}
+	if len(value) > maxValueSize {
+		return KVEntry{}, fmt.Errorf("%w: %d bytes, at most %d", ErrValueTooLarge, len(value), maxValueSize)
+	}
+
+	kv.lock.Lock()
+	defer kv.lock.Unlock()
+
+	current, ok := kv.entries[key]
+	if err := checkCAS(cas, current, ok); err != nil {
+		return KVEntry{}, err
+	}
+
+	kv.index++
+	entry := KVEntry{Key: key, Value: value, CreateIndex: kv.index, ModifyIndex: kv.index}
+	if ok {
+		entry.CreateIndex = current.CreateIndex
+	}
+	kv.entries[key] = entry
+	kv.notify(key)
+
+	return entry, nil
+}
+
+func (kv *KV) Delete(key string, cas *uint64) error {
+	kv.lock.Lock()
+	defer kv.lock.Unlock()
+
+	current, ok := kv.entries[key]
+	if err := checkCAS(cas, current, ok); err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	kv.index++
+	delete(kv.entries, key)
+	kv.notify(key)
+
+	return nil
+}
+
+// DeleteTree removes every key that starts with prefix and reports how many.
+func (kv *KV) DeleteTree(prefix string) int {
+	kv.lock.Lock()
+	defer kv.lock.Unlock()
+
+	deleted := make([]string, 0)
+	for key := range kv.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(kv.entries, key)
+			deleted = append(deleted, key)
+		}
+	}
+	if len(deleted) > 0 {
+		kv.index++
+		kv.notify(deleted...)
+	}
+	return len(deleted)
+}
+
+func checkCAS(cas *uint64, current KVEntry, ok bool) error {
+	switch {
+	case cas == nil:
+		return nil
+	case *cas == 0 && ok:
+		return fmt.Errorf("%w: key %s already exists", ErrPreconditionFailed, current.Key)
+	case *cas != 0 && (!ok || current.ModifyIndex != *cas):
+		return fmt.Errorf("%w: modify index is not %d", ErrPreconditionFailed, *cas)
+	default:
+		return nil
+	}
+}
+
+// Wait blocks until key, or with recurse any key under it as a prefix, changes
+// or ctx is done, as long as index is the current one. Any other index means
+// the caller is behind, e.g. after a restore, so it returns right away.
+func (kv *KV) Wait(ctx context.Context, key string, recurse bool, index uint64) {
+	kv.lock.Lock()
+	if index != kv.index {
+		kv.lock.Unlock()
+		return
+	}
+	waiter := &kvWaiter{key: key, recurse: recurse, changed: make(chan struct{})}
+	kv.waiters[waiter] = struct{}{}
+	kv.lock.Unlock()
+
+	select {
+	case <-waiter.changed:
+	case <-ctx.Done():
+		kv.lock.Lock()
+		delete(kv.waiters, waiter)
+		kv.lock.Unlock()
+	}
+}
+
+// notify wakes the waiters that watch one of the changed keys.
+func (kv *KV) notify(changed ...string) {
+	for waiter := range kv.waiters {
+		if slices.ContainsFunc(changed, waiter.watches) {
+			close(waiter.changed)
+			delete(kv.waiters, waiter)
+		}
+	}
+}
+
+func (kv *KV) notifyAll() {
+	for waiter := range kv.waiters {
+		close(waiter.changed)
+		delete(kv.waiters, waiter)
+	}
+}
+
+func (kv *KV) snapshot() []KVEntry {
+	kv.lock.RLock()
+	defer kv.lock.RUnlock()
+
+	return kv.list("")
+}
+
+func (kv *KV) restore(entries []KVEntry, mode RestoreMode) {
+	if mode == Merge && len(entries) == 0 {
+		return
+	}
+
+	kv.lock.Lock()
+	defer kv.lock.Unlock()
+
+	if mode == Replace {
+		clear(kv.entries)
+	}
+	for _, entry := range entries {
+		kv.entries[entry.Key] = entry
+		kv.index = max(kv.index, entry.ModifyIndex)
+	}
+	kv.index++
+	kv.notifyAll()
+}
+
+func NewKV() *KV {
+	return &KV{
+		entries: make(map[string]KVEntry),
+		waiters: make(map[*kvWaiter]struct{}),
+		lock:    sync.RWMutex{},
+	}
+}
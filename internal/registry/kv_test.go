@@ -0,0 +1,304 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_KV_CompareAndSwap(t *testing.T) {
+	// given
+	kv := NewKV()
+	zero := uint64(0)
+
+	// when
+	created, createErr := kv.Put("config/payments/timeout", "5s", &zero)
+	_, duplicateErr := kv.Put("config/payments/timeout", "6s", &zero)
+	stale := created.ModifyIndex + 1
+	_, staleErr := kv.Put("config/payments/timeout", "6s", &stale)
+	updated, updateErr := kv.Put("config/payments/timeout", "6s", &created.ModifyIndex)
+	staleDeleteErr := kv.Delete("config/payments/timeout", &created.ModifyIndex)
+
+	// then
+	if createErr != nil || updateErr != nil {
+		t.Fatalf("errors: got %v, %v, want none", createErr, updateErr)
+	}
+	if !errors.Is(duplicateErr, ErrPreconditionFailed) || !errors.Is(staleErr, ErrPreconditionFailed) || !errors.Is(staleDeleteErr, ErrPreconditionFailed) {
+		t.Fatalf("errors: got %v, %v, %v, want %v", duplicateErr, staleErr, staleDeleteErr, ErrPreconditionFailed)
+	}
+	if updated.CreateIndex != created.CreateIndex || updated.ModifyIndex <= created.ModifyIndex {
+		t.Fatalf("indexes: got %+v after %+v", updated, created)
+	}
+	if entry, _, _ := kv.Get("config/payments/timeout"); entry.Value != "6s" {
+		t.Fatalf("value: got %q, want %q", entry.Value, "6s")
+	}
+}
+
+func Test_KV_InvalidKeys(t *testing.T) {
+	// given
+	kv := NewKV()
+	keys := []string{"", "/config", "config/", "config//timeout", "config/../secret", strings.Repeat("k", maxKeyLength+1)}
+
+	for _, key := range keys {
+		// when
+		_, err := kv.Put(key, "value", nil)
+
+		// then
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("key %q: got %v, want %v", key, err, ErrInvalidKey)
+		}
+	}
+}
+
+func Test_KV_WaitWakesOnPrefix(t *testing.T) {
+	// given
+	kv := NewKV()
+	if _, err := kv.Put("config/search/timeout", "1s", nil); err != nil {
+		t.Fatal(err)
+	}
+	_, index := kv.List("config/payments/")
+
+	woken := make(chan struct{})
+	go func() {
+		kv.Wait(context.Background(), "config/payments/", true, index)
+		close(woken)
+	}()
+
+	// when
+	time.Sleep(10 * time.Millisecond)
+	if _, err := kv.Put("config/search/retries", "3", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kv.Put("config/payments", "shared", nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-woken:
+		t.Fatal("woken by a key outside of the prefix")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if _, err := kv.Put("config/payments/timeout", "5s", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	select {
+	case <-woken:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the prefix to change")
+	}
+}
+
+func Test_KV_WaitOnKeyIgnoresSiblings(t *testing.T) {
+	// given
+	kv := NewKV()
+	if _, err := kv.Put("config/timeout", "1s", nil); err != nil {
+		t.Fatal(err)
+	}
+	_, index, _ := kv.Get("config/timeout")
+
+	woken := make(chan struct{})
+	go func() {
+		kv.Wait(context.Background(), "config/timeout", false, index)
+		close(woken)
+	}()
+
+	// when
+	time.Sleep(10 * time.Millisecond)
+	for _, key := range []string{"config/timeouts", "config/timeout/read"} {
+		if _, err := kv.Put(key, "2s", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-woken:
+		t.Fatal("woken by a key next to the watched one")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if _, err := kv.Put("config/timeout", "3s", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	select {
+	case <-woken:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the key to change")
+	}
+}
+
+func Test_KVHandler(t *testing.T) {
+	// given
+	store := NewStore()
+	handler := NewHandler(store)
+
+	call := func(method string, target string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return recorder
+	}
+
+	// when
+	created := call(http.MethodPut, "/v2/kv/config/payments/timeout?cas=0", "5s")
+	conflict := call(http.MethodPut, "/v2/kv/config/payments/timeout?cas=0", "6s")
+	call(http.MethodPut, "/v2/kv/config/payments/retries", "3")
+	call(http.MethodPut, "/v2/kv/config/search/timeout", "1s")
+	tooLarge := call(http.MethodPut, "/v2/kv/config/blob", strings.Repeat("x", maxValueSize+1))
+
+	get := call(http.MethodGet, "/v2/kv/config/payments/timeout", "")
+	list := call(http.MethodGet, "/v2/kv/config/payments/?recurse", "")
+	missing := call(http.MethodGet, "/v2/kv/config/missing", "")
+
+	deleteTree := call(http.MethodDelete, "/v2/kv/config/payments/?recurse", "")
+	afterDelete := call(http.MethodGet, "/v2/kv/?recurse", "")
+
+	// then
+	if created.Code != http.StatusOK || conflict.Code != http.StatusPreconditionFailed || tooLarge.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("puts: got %v, %v, %v", created.Code, conflict.Code, tooLarge.Code)
+	}
+
+	var entry KVEntry
+	if err := json.Unmarshal(get.Body.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Value != "5s" || get.Header().Get(IndexHeader) != "3" {
+		t.Fatalf("get: got %+v with index %q", entry, get.Header().Get(IndexHeader))
+	}
+
+	var listed KVResponse
+	if err := json.Unmarshal(list.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.Entries) != 2 || listed.Entries[0].Key != "config/payments/retries" || listed.Index != 3 {
+		t.Fatalf("list: got %+v", listed)
+	}
+	if missing.Code != http.StatusNotFound {
+		t.Fatalf("missing: got %v, want %v", missing.Code, http.StatusNotFound)
+	}
+
+	var remaining KVResponse
+	if err := json.Unmarshal(afterDelete.Body.Bytes(), &remaining); err != nil {
+		t.Fatal(err)
+	}
+	if deleteTree.Code != http.StatusNoContent || len(remaining.Entries) != 1 || remaining.Entries[0].Key != "config/search/timeout" {
+		t.Fatalf("after delete: got %v, %+v", deleteTree.Code, remaining)
+	}
+}
+
+func Test_KVHandler_AuditsMutationsAndKeepsTheRoot(t *testing.T) {
+	// given
+	store := NewStore()
+	handler := NewHandler(store)
+
+	call := func(method string, target string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		request.Header.Set(callerHeader, "deployer")
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// when
+	call(http.MethodPut, "/v2/kv/config/payments/timeout", "5s")
+	call(http.MethodPut, "/v2/kv/config/search/timeout", "1s")
+	deleteRoot := call(http.MethodDelete, "/v2/kv/?recurse", "")
+	call(http.MethodDelete, "/v2/kv/config/search/timeout", "")
+	call(http.MethodDelete, "/v2/kv/config/payments/?recurse", "")
+
+	// then
+	if deleteRoot.Code != http.StatusBadRequest {
+		t.Fatalf("delete root: got %v, want %v", deleteRoot.Code, http.StatusBadRequest)
+	}
+	entries := store.AuditLog("", time.Time{})
+	if len(entries) != 4 {
+		t.Fatalf("len(entries) = %d, want 4", len(entries))
+	}
+
+	want := []struct {
+		action AuditAction
+		key    string
+	}{
+		{AuditKVPut, "config/payments/timeout"},
+		{AuditKVPut, "config/search/timeout"},
+		{AuditKVDelete, "config/search/timeout"},
+		{AuditKVDeleteTree, "config/payments/"},
+	}
+	for i, entry := range entries {
+		if entry.Action != want[i].action || entry.Key != want[i].key || entry.Actor != "192.0.2.1:1234" || entry.ClaimedActor != "deployer" {
+			t.Fatalf("entry %d: got %+v, want %s of %s", i, entry, want[i].action, want[i].key)
+		}
+	}
+}
+
+func Test_KVHandler_BlockingRead(t *testing.T) {
+	// given
+	store := NewStore()
+	server := httptest.NewServer(NewHandler(store))
+	defer server.Close()
+
+	entry, err := store.KV().Put("config/payments/timeout", "5s", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := fmt.Sprintf("%s/v2/kv/config/payments/timeout?index=%d&wait=5s", server.URL, entry.ModifyIndex)
+
+	// when
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		if _, err := store.KV().Put("config/payments/timeout", "6s", nil); err != nil {
+			t.Error(err)
+		}
+	}()
+	started := time.Now()
+	resp, err := http.Get(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var got KVEntry
+	if err = json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	if got.Value != "6s" || resp.Header.Get(IndexHeader) != "2" {
+		t.Fatalf("blocking read: got %+v with index %q", got, resp.Header.Get(IndexHeader))
+	}
+	if elapsed := time.Since(started); elapsed > 4*time.Second {
+		t.Fatalf("blocking read waited %v, want to return on change", elapsed)
+	}
+}
+
+func Test_Snapshot_IncludesKV(t *testing.T) {
+	// given
+	source := NewStore()
+	if _, err := source.KV().Put("config/payments/timeout", "5s", nil); err != nil {
+		t.Fatal(err)
+	}
+	target := NewStore()
+	if _, err := target.KV().Put("config/stale", "1", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	err := target.Restore(source.Snapshot(), Replace)
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, index := target.KV().List("")
+	if len(entries) != 1 || entries[0].Value != "5s" {
+		t.Fatalf("entries: got %+v, want the restored one", entries)
+	}
+	if index <= entries[0].ModifyIndex {
+		t.Fatalf("index: got %d, want it past the restored modify index", index)
+	}
+}
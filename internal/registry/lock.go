@@ -0,0 +1,206 @@
+package registry
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLockHeld        = errors.New("lock held by another instance")
+	ErrLockHolderDown  = errors.New("lock holder is down")
+	ErrInvalidLockName = errors.New("invalid lock name")
+)
+
+const maxLockNameLength = 256
+
+// Lock is held by one registered instance until it releases the lock, is
+// deregistered or goes down. Token grows with every acquisition, so a former
+// leader can be fenced off by the services it talks to.
+type Lock struct {
+	Name       string    `json:"name"`
+	ServiceID  string    `json:"service_id"`
+	InstanceID string    `json:"instance_id"`
+	Token      uint64    `json:"token"`
+	AcquiredAt time.Time `json:"acquired_at"`
+
+	// version is the holder's service version when the lock was taken, only
+	// later changes release it.
+	version uint64
+}
+
+type locks struct {
+	held     map[string]Lock
+	token    uint64
+	released map[string]chan struct{}
+	lock     sync.Mutex
+}
+
+func checkLockName(name string) error {
+	if name == "" || len(name) > maxLockNameLength {
+		return fmt.Errorf("%w: %q", ErrInvalidLockName, name)
+	}
+	return nil
+}
+
+// acquire takes the lock for the instance, which already holding it is a no-op
+// for. When another instance holds it, the returned channel is closed on release.
+func (l *locks) acquire(name string, serviceID string, instanceID string, version uint64, now time.Time) (Lock, <-chan struct{}, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if held, ok := l.held[name]; ok {
+		if held.ServiceID == serviceID && held.InstanceID == instanceID {
+			return held, nil, nil
+		}
+		released, ok := l.released[name]
+		if !ok {
+			released = make(chan struct{})
+			l.released[name] = released
+		}
+		return Lock{}, released, fmt.Errorf("%w: %s is held by %s/%s", ErrLockHeld, name, held.ServiceID, held.InstanceID)
+	}
+
+	l.token++
+	lock := Lock{Name: name, ServiceID: serviceID, InstanceID: instanceID, Token: l.token, AcquiredAt: now, version: version}
+	l.held[name] = lock
+	return lock, nil, nil
+}
+
+func (l *locks) release(name string, serviceID string, instanceID string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	held, ok := l.held[name]
+	if !ok {
+		return nil
+	}
+	if held.ServiceID != serviceID || held.InstanceID != instanceID {
+		return fmt.Errorf("%w: %s is held by %s/%s", ErrLockHeld, name, held.ServiceID, held.InstanceID)
+	}
+	l.releaseLocked(name)
+	return nil
+}
+
+// releaseHolder releases every lock the instance took before version and
+// returns their names.
+func (l *locks) releaseHolder(serviceID string, instanceID string, version uint64) []string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var names []string
+	for name, held := range l.held {
+		if held.ServiceID == serviceID && held.InstanceID == instanceID && held.version < version {
+			l.releaseLocked(name)
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (l *locks) releaseLocked(name string) {
+	delete(l.held, name)
+	if released, ok := l.released[name]; ok {
+		close(released)
+		delete(l.released, name)
+	}
+}
+
+func (l *locks) get(name string) (Lock, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	lock, ok := l.held[name]
+	return lock, ok
+}
+
+func (l *locks) list() []Lock {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	result := make([]Lock, 0, len(l.held))
+	for _, lock := range l.held {
+		result = append(result, lock)
+	}
+	slices.SortFunc(result, func(a, b Lock) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return result
+}
+
+func newLocks() *locks {
+	return &locks{
+		held:     make(map[string]Lock),
+		released: make(map[string]chan struct{}),
+		lock:     sync.Mutex{},
+	}
+}
+
+// acquireLock retries until the lock is free or ctx is done, so a ctx that is
+// already done makes a single attempt.
+func (s *Store) acquireLock(ctx context.Context, name string, serviceID string, instanceID string) (Lock, error) {
+	if err := checkLockName(name); err != nil {
+		return Lock{}, err
+	}
+
+	for {
+		lock, released, err := s.tryAcquireLock(name, serviceID, instanceID)
+		if !errors.Is(err, ErrLockHeld) {
+			return lock, err
+		}
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return Lock{}, err
+		}
+	}
+}
+
+func (s *Store) tryAcquireLock(name string, serviceID string, instanceID string) (Lock, <-chan struct{}, error) {
+	// The shard stays locked while the lock is taken, so the lock keeps the
+	// version the holder was checked at and a later removal or status change
+	// releases it again once recorded.
+	sh := s.shard(serviceID)
+	sh.lock.RLock()
+	defer sh.lock.RUnlock()
+
+	instance, ok := sh.serviceIDToInstances[serviceID][instanceID]
+	if !ok {
+		return Lock{}, nil, ErrNotFound
+	}
+	now := s.now()
+	if instance.hostStatus(now).Status == Down {
+		return Lock{}, nil, fmt.Errorf("%w: %s/%s", ErrLockHolderDown, serviceID, instanceID)
+	}
+
+	return s.locks.acquire(name, serviceID, instanceID, sh.serviceIDToVersion[serviceID], now)
+}
+
+func (s *Store) releaseLock(name string, serviceID string, instanceID string) error {
+	return s.locks.release(name, serviceID, instanceID)
+}
+
+// releaseLocksOf is called by record for every event, so the locks of an
+// instance go away however it was removed or marked down.
+func (s *Store) releaseLocksOf(event Event) {
+	if event.Type != Removed && event.Instance.Status != Down {
+		return
+	}
+	if names := s.locks.releaseHolder(event.ServiceID, event.Instance.InstanceID, event.Version); len(names) > 0 {
+		slog.Info("released locks of instance", "serviceID", event.ServiceID, "instanceID", event.Instance.InstanceID, "status", event.Instance.Status, "locks", names)
+	}
+}
+
+func (s *Store) Lock(name string) (Lock, bool) {
+	return s.locks.get(name)
+}
+
+func (s *Store) Locks() []Lock {
+	return s.locks.list()
+}
@@ -0,0 +1,178 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Locks_AcquireAndRelease(t *testing.T) {
+	// given
+	store := NewStore()
+	registerLockHolders(t, store, "a", "b")
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// when
+	first, firstErr := store.acquireLock(done, "leader", "jobs", "a")
+	again, againErr := store.acquireLock(done, "leader", "jobs", "a")
+	_, heldErr := store.acquireLock(done, "leader", "jobs", "b")
+	foreignReleaseErr := store.releaseLock("leader", "jobs", "b")
+	releaseErr := store.releaseLock("leader", "jobs", "a")
+	second, secondErr := store.acquireLock(done, "leader", "jobs", "b")
+	_, missingErr := store.acquireLock(done, "leader", "jobs", "c")
+
+	// then
+	if firstErr != nil || againErr != nil || releaseErr != nil || secondErr != nil {
+		t.Fatalf("errors: got %v, %v, %v, %v, want none", firstErr, againErr, releaseErr, secondErr)
+	}
+	if again != first {
+		t.Fatalf("reacquire: got %+v, want %+v", again, first)
+	}
+	if !errors.Is(heldErr, ErrLockHeld) || !errors.Is(foreignReleaseErr, ErrLockHeld) {
+		t.Fatalf("held errors: got %v, %v, want %v", heldErr, foreignReleaseErr, ErrLockHeld)
+	}
+	if !errors.Is(missingErr, ErrNotFound) {
+		t.Fatalf("unregistered holder: got %v, want %v", missingErr, ErrNotFound)
+	}
+	if second.InstanceID != "b" || second.Token <= first.Token {
+		t.Fatalf("second holder: got %+v after %+v", second, first)
+	}
+}
+
+func Test_Locks_ReleasedWithInstance(t *testing.T) {
+	// given
+	store := NewStore()
+	registerLockHolders(t, store, "a", "b")
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.acquireLock(done, "leader", "jobs", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.acquireLock(done, "scheduler", "jobs", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	store.Put("jobs", "a", Down)
+	store.Remove("jobs", "b")
+	_, downErr := store.acquireLock(done, "leader", "jobs", "a")
+
+	// then
+	if locks := store.Locks(); len(locks) != 0 {
+		t.Fatalf("locks: got %+v, want none", locks)
+	}
+	if !errors.Is(downErr, ErrLockHolderDown) {
+		t.Fatalf("down holder: got %v, want %v", downErr, ErrLockHolderDown)
+	}
+}
+
+func Test_Locks_KeptForLaterHolder(t *testing.T) {
+	// given
+	store := NewStore()
+	registerLockHolders(t, store, "a")
+	events, cancelEvents := store.Subscribe(8, nil)
+	defer cancelEvents()
+	store.Remove("jobs", "a")
+	removed := <-events
+	registerLockHolders(t, store, "a")
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.acquireLock(done, "leader", "jobs", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	store.releaseLocksOf(removed)
+
+	// then
+	if _, ok := store.Lock("leader"); !ok {
+		t.Fatal("lock taken after the removal was released by it")
+	}
+}
+
+func Test_Locks_BlockingAcquire(t *testing.T) {
+	// given
+	store := NewStore()
+	registerLockHolders(t, store, "a", "b")
+	if _, err := store.acquireLock(context.Background(), "leader", "jobs", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		store.Remove("jobs", "a")
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lock, err := store.acquireLock(ctx, "leader", "jobs", "b")
+
+	// then
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock.InstanceID != "b" {
+		t.Fatalf("holder: got %+v, want b", lock)
+	}
+}
+
+func Test_LockHandlers(t *testing.T) {
+	// given
+	store := NewStore()
+	registerLockHolders(t, store, "a", "b")
+	handler := NewHandler(store)
+
+	call := func(method string, target string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return recorder
+	}
+
+	// when
+	acquired := call(http.MethodPut, "/v2/locks/leader", `{"service_id":"jobs","instance_id":"a"}`)
+	conflict := call(http.MethodPut, "/v2/locks/leader?wait=10ms", `{"service_id":"jobs","instance_id":"b"}`)
+	badRequest := call(http.MethodPut, "/v2/locks/leader", `{"service_id":"jobs"}`)
+	get := call(http.MethodGet, "/v2/locks/leader", "")
+	list := call(http.MethodGet, "/v2/locks", "")
+	released := call(http.MethodDelete, "/v2/locks/leader", `{"service_id":"jobs","instance_id":"a"}`)
+	afterRelease := call(http.MethodGet, "/v2/locks/leader", "")
+
+	// then
+	if acquired.Code != http.StatusOK || conflict.Code != http.StatusConflict || badRequest.Code != http.StatusBadRequest {
+		t.Fatalf("acquire: got %v, %v, %v", acquired.Code, conflict.Code, badRequest.Code)
+	}
+
+	var lock Lock
+	if err := json.Unmarshal(get.Body.Bytes(), &lock); err != nil {
+		t.Fatal(err)
+	}
+	if lock.Name != "leader" || lock.InstanceID != "a" {
+		t.Fatalf("get: got %+v", lock)
+	}
+
+	var locksResp LocksResponse
+	if err := json.Unmarshal(list.Body.Bytes(), &locksResp); err != nil {
+		t.Fatal(err)
+	}
+	if len(locksResp.Locks) != 1 {
+		t.Fatalf("list: got %+v", locksResp)
+	}
+	if released.Code != http.StatusNoContent || afterRelease.Code != http.StatusNotFound {
+		t.Fatalf("release: got %v, then %v", released.Code, afterRelease.Code)
+	}
+}
+
+func registerLockHolders(t *testing.T, store *Store, instanceIDs ...string) {
+	for _, instanceID := range instanceIDs {
+		if _, _, err := store.register("jobs", Instance{ID: instanceID, Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+			t.Fatal(err)
+		}
+		store.Put("jobs", instanceID, Healthy)
+	}
+}
@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"sync"
+)
+
+const DefaultNamespace = "default"
+
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+func ValidNamespace(namespace string) bool {
+	return namespacePattern.MatchString(namespace)
+}
+
+// Namespaces partitions the registry into one store per namespace, so the
+// same service ID may be registered by several teams.
+type Namespaces map[string]*Store
+
+// Subscribe merges the events of every namespace into one channel.
+func (n Namespaces) Subscribe(buffer int, dropped func(Event)) (<-chan Event, func()) {
+	merged := make(chan Event, buffer)
+	wg := &sync.WaitGroup{}
+	cancels := make([]func(), 0, len(n))
+	for _, store := range n {
+		events, cancel := store.Subscribe(buffer, dropped)
+		cancels = append(cancels, cancel)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for event := range events {
+				select {
+				case merged <- event:
+				default:
+					slog.Warn("dropping registry event for slow subscriber", "type", event.Type, "namespace", event.Namespace, "serviceID", event.ServiceID)
+					if dropped != nil {
+						dropped(event)
+					}
+				}
+			}
+		}()
+	}
+
+	cancel := sync.OnceFunc(func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+		wg.Wait()
+		close(merged)
+	})
+	return merged, cancel
+}
+
+func (n Namespaces) HasNamespace(namespace string) bool {
+	_, ok := n[namespace]
+	return ok
+}
+
+// NewNamespaceHandler serves /ns/{namespace}/... with the handler of that
+// namespace, which sees the rest of the path as if it was mounted at the root.
+func NewNamespaceHandler(handlers map[string]http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		namespace := request.PathValue("namespace")
+		handler, ok := handlers[namespace]
+		if !ok {
+			http.Error(writer, fmt.Sprintf("unknown namespace %q", namespace), http.StatusNotFound)
+			return
+		}
+		http.StripPrefix("/ns/"+namespace, handler).ServeHTTP(writer, request)
+	})
+}
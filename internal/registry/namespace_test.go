@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_NamespaceHandler_IsolatesNamespaces(t *testing.T) {
+	// given
+	defaultStore := NewStore()
+	teamStore := NewStore(WithNamespace("team-a"), WithStoreLimits(StoreLimits{MaxInstancesPerService: 1}))
+	teamAccess := NewAccessControl(ACL{MutationCIDRs: []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}})
+	handlers := map[string]http.Handler{
+		DefaultNamespace: NewHandler(defaultStore),
+		"team-a":         NewHandler(teamStore, WithAccessControl(teamAccess)),
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", handlers[DefaultNamespace])
+	mux.Handle("/ns/{namespace}/", NewNamespaceHandler(handlers))
+
+	call := func(method string, target string, remoteAddr string) int {
+		request := httptest.NewRequest(method, target, strings.NewReader(`{"host":"127.0.0.1:8080"}`))
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// when
+	legacy := call(http.MethodPut, "/v2/services/api/instances/a", "192.0.2.1:1234")
+	prefixedDefault := call(http.MethodPut, "/ns/default/v2/services/api/instances/b", "192.0.2.1:1234")
+	team := call(http.MethodPut, "/ns/team-a/v2/services/api/instances/a", "10.1.2.3:1234")
+	outsider := call(http.MethodPut, "/ns/team-a/v2/services/api/instances/c", "192.0.2.1:1234")
+	overQuota := call(http.MethodPut, "/ns/team-a/v2/services/api/instances/d", "10.1.2.3:1234")
+	unknown := call(http.MethodGet, "/ns/team-b/v2/services/api/instances", "192.0.2.1:1234")
+
+	// then
+	if legacy != http.StatusCreated || prefixedDefault != http.StatusCreated || team != http.StatusCreated {
+		t.Fatalf("registrations: got %v, %v, %v, want %v", legacy, prefixedDefault, team, http.StatusCreated)
+	}
+	if outsider != http.StatusForbidden {
+		t.Fatalf("outsider: got %v, want %v", outsider, http.StatusForbidden)
+	}
+	if overQuota != http.StatusForbidden {
+		t.Fatalf("over quota: got %v, want %v", overQuota, http.StatusForbidden)
+	}
+	if unknown != http.StatusNotFound {
+		t.Fatalf("unknown namespace: got %v, want %v", unknown, http.StatusNotFound)
+	}
+	if got := len(defaultStore.Get("api")); got != 2 {
+		t.Fatalf("default namespace: got %d instances, want 2", got)
+	}
+	if got := len(teamStore.Get("api")); got != 1 {
+		t.Fatalf("team namespace: got %d instances, want 1", got)
+	}
+}
+
+func Test_Namespaces_Subscribe(t *testing.T) {
+	// given
+	defaultStore := NewStore()
+	teamStore := NewStore(WithNamespace("team-a"))
+	events, cancel := Namespaces{DefaultNamespace: defaultStore, "team-a": teamStore}.Subscribe(8, nil)
+
+	// when
+	if _, _, err := defaultStore.register("api", Instance{ID: "a", Host: "a:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := teamStore.register("api", Instance{ID: "a", Host: "a:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+
+	// then
+	namespaces := make(map[string]bool)
+	for range 2 {
+		select {
+		case event := <-events:
+			namespaces[event.Namespace] = true
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for events")
+		}
+	}
+	if !namespaces[""] || !namespaces["team-a"] {
+		t.Fatalf("namespaces: got %v, want default and team-a", namespaces)
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Fatal("merged channel is still open after cancel")
+	}
+}
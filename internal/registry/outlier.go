@@ -0,0 +1,288 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+var ErrInvalidErrorClass = errors.New("invalid error class")
+
+var outlierActor = Actor{Name: "outlier-detector"}
+
+type ErrorClass string
+
+const (
+	Success      ErrorClass = "success"
+	ServerError  ErrorClass = "5xx"
+	GatewayError ErrorClass = "gateway"
+	ConnectError ErrorClass = "connect"
+	TimeoutError ErrorClass = "timeout"
+	LocalError   ErrorClass = "local"
+)
+
+func (c ErrorClass) valid() bool {
+	switch c {
+	case Success, ServerError, GatewayError, ConnectError, TimeoutError, LocalError:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c ErrorClass) failure() bool {
+	return c != Success
+}
+
+// serverError is true for the 5xx responses counted as consecutive failures,
+// gateway errors being 502, 503 and 504 responses.
+func (c ErrorClass) serverError() bool {
+	return c == ServerError || c == GatewayError
+}
+
+type Ejection struct {
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+	Count  int       `json:"count"`
+}
+
+func (e *Ejection) equal(other *Ejection) bool {
+	if e == nil || other == nil {
+		return e == other
+	}
+	return e.Reason == other.Reason && e.Since.Equal(other.Since) && e.Until.Equal(other.Until) && e.Count == other.Count
+}
+
+func (e *Ejection) active(now time.Time) bool {
+	return e != nil && now.Before(e.Until)
+}
+
+func (e *Ejection) expiresAt() time.Time {
+	if e == nil {
+		return time.Time{}
+	}
+	return e.Until
+}
+
+type OutlierPolicy struct {
+	ConsecutiveFailures int
+	Interval            time.Duration
+	BaseEjectionTime    time.Duration
+	MaxEjectionTime     time.Duration
+	MaxEjectionPercent  int
+	MinRequestVolume    int
+	MinHosts            int
+	StdevFactor         float64
+}
+
+func DefaultOutlierPolicy() OutlierPolicy {
+	return OutlierPolicy{
+		ConsecutiveFailures: 5,
+		Interval:            10 * time.Second,
+		BaseEjectionTime:    30 * time.Second,
+		MaxEjectionTime:     5 * time.Minute,
+		MaxEjectionPercent:  10,
+		MinRequestVolume:    100,
+		MinHosts:            5,
+		StdevFactor:         1.9,
+	}
+}
+
+type outlierKey struct {
+	serviceID  string
+	instanceID string
+}
+
+type outlierStats struct {
+	consecutive    int
+	successes      int
+	failures       int
+	ejections      int
+	lastErrorClass ErrorClass
+}
+
+// OutlierDetector ejects instances based on the outcome of real calls reported
+// by clients, following Envoy's consecutive failure and success rate detection.
+type OutlierDetector struct {
+	store  *Store
+	policy OutlierPolicy
+	stats  map[outlierKey]*outlierStats
+	lock   sync.Mutex
+}
+
+func (d *OutlierDetector) Report(serviceID string, instanceID string, errorClass ErrorClass, count int) error {
+	if errorClass == "" {
+		errorClass = Success
+	}
+	if !errorClass.valid() {
+		return fmt.Errorf("%w: %q", ErrInvalidErrorClass, errorClass)
+	}
+	if _, ok := d.store.Find(serviceID, instanceID); !ok {
+		return ErrNotFound
+	}
+	count = max(count, 1)
+
+	d.lock.Lock()
+	key := outlierKey{serviceID: serviceID, instanceID: instanceID}
+	stats, ok := d.stats[key]
+	if !ok {
+		stats = &outlierStats{}
+		d.stats[key] = stats
+	}
+
+	if !errorClass.failure() {
+		stats.successes += count
+		stats.consecutive = 0
+		d.lock.Unlock()
+		return nil
+	}
+
+	stats.failures += count
+	stats.lastErrorClass = errorClass
+	if !errorClass.serverError() {
+		d.lock.Unlock()
+		return nil
+	}
+	stats.consecutive += count
+	tripped := d.policy.ConsecutiveFailures > 0 && stats.consecutive >= d.policy.ConsecutiveFailures
+	d.lock.Unlock()
+
+	if tripped {
+		reason := fmt.Sprintf("%d consecutive failures, last %s", d.policy.ConsecutiveFailures, errorClass)
+		d.eject(key, reason)
+	}
+	return nil
+}
+
+func (d *OutlierDetector) ReportHost(serviceID string, host string, errorClass ErrorClass, count int) error {
+	found := false
+	for _, hostStatus := range d.store.Get(serviceID) {
+		if hostStatus.Host != host {
+			continue
+		}
+		found = true
+		if err := d.Report(serviceID, hostStatus.InstanceID, errorClass, count); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (d *OutlierDetector) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			d.sweep()
+		}
+	}
+}
+
+// sweep puts back instances whose ejection is over, ejects instances whose
+// success rate over the last interval is far below their peers and starts a
+// new interval.
+func (d *OutlierDetector) sweep() {
+	d.store.expireEjections(outlierActor)
+
+	d.lock.Lock()
+	byService := make(map[string]map[string]float64)
+	for key, stats := range d.stats {
+		if _, ok := d.store.Find(key.serviceID, key.instanceID); !ok {
+			delete(d.stats, key)
+			continue
+		}
+		if volume := stats.successes + stats.failures; volume >= d.policy.MinRequestVolume && volume > 0 {
+			if _, ok := byService[key.serviceID]; !ok {
+				byService[key.serviceID] = make(map[string]float64)
+			}
+			byService[key.serviceID][key.instanceID] = float64(stats.successes) / float64(volume)
+		}
+		if stats.failures == 0 && stats.consecutive == 0 && stats.ejections > 0 {
+			stats.ejections--
+		}
+		stats.successes = 0
+		stats.failures = 0
+		if stats.ejections == 0 && stats.consecutive == 0 {
+			delete(d.stats, key)
+		}
+	}
+	d.lock.Unlock()
+
+	for serviceID, rates := range byService {
+		if len(rates) < d.policy.MinHosts {
+			continue
+		}
+		mean, stdev := meanStdev(rates)
+		threshold := mean - d.policy.StdevFactor*stdev
+		for instanceID, rate := range rates {
+			if rate < threshold {
+				reason := fmt.Sprintf("success rate %.1f%% below threshold %.1f%%", rate*100, threshold*100)
+				d.eject(outlierKey{serviceID: serviceID, instanceID: instanceID}, reason)
+			}
+		}
+	}
+}
+
+func (d *OutlierDetector) eject(key outlierKey, reason string) {
+	d.lock.Lock()
+	count := 1
+	if stats, ok := d.stats[key]; ok {
+		count = stats.ejections + 1
+	}
+	d.lock.Unlock()
+
+	now := d.store.now()
+	duration := min(d.policy.BaseEjectionTime*time.Duration(count), d.policy.MaxEjectionTime)
+	ejection := Ejection{Reason: reason, Since: now, Until: now.Add(duration), Count: count}
+
+	ejected, err := d.store.eject(key.serviceID, key.instanceID, ejection, d.policy.MaxEjectionPercent)
+	if err != nil || !ejected {
+		return
+	}
+
+	// A sweep may have dropped the stats while the store was ejecting.
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	stats, ok := d.stats[key]
+	if !ok {
+		stats = &outlierStats{}
+		d.stats[key] = stats
+	}
+	stats.ejections = count
+	stats.consecutive = 0
+}
+
+func meanStdev(values map[string]float64) (float64, float64) {
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+
+	var variance float64
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+func NewOutlierDetector(store *Store, policy OutlierPolicy) *OutlierDetector {
+	return &OutlierDetector{
+		store:  store,
+		policy: policy,
+		stats:  make(map[outlierKey]*outlierStats),
+		lock:   sync.Mutex{},
+	}
+}
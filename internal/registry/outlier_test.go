@@ -0,0 +1,215 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_OutlierDetector_EjectsOnConsecutiveFailures(t *testing.T) {
+	// given
+	store := NewStore()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	for _, instanceID := range []string{"a", "b", "c"} {
+		if _, _, err := store.register("one", Instance{ID: instanceID, Host: instanceID + ":8080"}, "", systemActor); err != nil {
+			t.Fatal(err)
+		}
+		store.Put("one", instanceID, Healthy)
+	}
+
+	policy := DefaultOutlierPolicy()
+	policy.ConsecutiveFailures = 3
+	policy.MaxEjectionPercent = 50
+	detector := NewOutlierDetector(store, policy)
+
+	report := func(instanceID string, errorClass ErrorClass) {
+		if err := detector.Report("one", instanceID, errorClass, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// when
+	report("a", ServerError)
+	report("a", ServerError)
+	report("a", Success)
+	report("a", ServerError)
+	report("a", ServerError)
+	notYet, _ := store.Find("one", "a")
+	report("a", GatewayError)
+	first, _ := store.Find("one", "a")
+
+	for range 3 {
+		report("b", ServerError)
+	}
+	capped, _ := store.Find("one", "b")
+
+	now = first.Ejection.Until
+	for range 3 {
+		report("a", TimeoutError)
+		report("a", ConnectError)
+	}
+	notServerErrors, _ := store.Find("one", "a")
+	for range 3 {
+		report("a", ServerError)
+	}
+	second, _ := store.Find("one", "a")
+
+	// then
+	if notYet.Ejection != nil || notYet.Status != Healthy {
+		t.Fatalf("before threshold: got %+v, want healthy without ejection", notYet)
+	}
+	if first.Ejection == nil || first.Status != Down || first.Health != Healthy {
+		t.Fatalf("first ejection: got status %s health %s ejection %v", first.Status, first.Health, first.Ejection)
+	}
+	if got := first.Ejection.Until.Sub(first.Ejection.Since); got != policy.BaseEjectionTime {
+		t.Fatalf("first ejection time: got %v, want %v", got, policy.BaseEjectionTime)
+	}
+	if capped.Ejection != nil {
+		t.Fatalf("ejection beyond max percent: got %v, want none", capped.Ejection)
+	}
+	if notServerErrors.Ejection != nil {
+		t.Fatalf("after timeouts and connect errors: got %v, want no ejection", notServerErrors.Ejection)
+	}
+	if second.Ejection == nil || second.Ejection.Count != 2 {
+		t.Fatalf("second ejection: got %v, want count 2", second.Ejection)
+	}
+	if got := second.Ejection.Until.Sub(second.Ejection.Since); got != 2*policy.BaseEjectionTime {
+		t.Fatalf("second ejection time: got %v, want %v", got, 2*policy.BaseEjectionTime)
+	}
+}
+
+func Test_OutlierDetector_EjectsOnLowSuccessRate(t *testing.T) {
+	// given
+	store := NewStore()
+	policy := DefaultOutlierPolicy()
+	policy.ConsecutiveFailures = 0
+	policy.MinHosts = 5
+	policy.MinRequestVolume = 10
+	policy.MaxEjectionPercent = 100
+	detector := NewOutlierDetector(store, policy)
+
+	for i := range 6 {
+		instanceID := fmt.Sprintf("i%d", i)
+		if _, _, err := store.register("one", Instance{ID: instanceID, Host: instanceID + ":8080"}, "", systemActor); err != nil {
+			t.Fatal(err)
+		}
+		failures := 0
+		if i == 5 {
+			failures = 8
+		}
+		if err := detector.Report("one", instanceID, Success, 100-failures*10); err != nil {
+			t.Fatal(err)
+		}
+		if failures > 0 {
+			if err := detector.Report("one", instanceID, ServerError, failures*10); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// when
+	detector.sweep()
+
+	// then
+	for _, hostStatus := range store.Get("one") {
+		ejected := hostStatus.Ejection != nil
+		if ejected != (hostStatus.InstanceID == "i5") {
+			t.Fatalf("instance %s: ejected %v", hostStatus.InstanceID, ejected)
+		}
+	}
+}
+
+func Test_OutlierDetector_SweepEndsEjections(t *testing.T) {
+	// given
+	store := NewStore()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	for _, instanceID := range []string{"a", "b"} {
+		if _, _, err := store.register("one", Instance{ID: instanceID, Host: instanceID + ":8080"}, "", systemActor); err != nil {
+			t.Fatal(err)
+		}
+		store.Put("one", instanceID, Healthy)
+	}
+	policy := DefaultOutlierPolicy()
+	policy.ConsecutiveFailures = 1
+	detector := NewOutlierDetector(store, policy)
+	if err := detector.Report("one", "a", ServerError, 1); err != nil {
+		t.Fatal(err)
+	}
+	events, cancel := store.Subscribe(8, nil)
+	defer cancel()
+
+	// when
+	detector.sweep()
+	now = now.Add(policy.BaseEjectionTime)
+	detector.sweep()
+
+	// then
+	event := <-events
+	if event.Type != StatusChanged || event.Previous.Status != Down || event.Instance.Status != Healthy || event.Actor != outlierActor.Name {
+		t.Fatalf("event: got %+v, want status change from down to healthy", event)
+	}
+	select {
+	case event = <-events:
+		t.Fatalf("unexpected event: %+v", event)
+	default:
+	}
+}
+
+func Test_ReportCallHandler(t *testing.T) {
+	// given
+	store := NewStore()
+	reportHandler := NewHandler(store)
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(body string) int {
+		recorder := httptest.NewRecorder()
+		reportHandler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v2/services/one/reports", strings.NewReader(body)))
+		return recorder.Code
+	}
+
+	// when
+	byInstance := call(`{"instance_id":"a","error_class":"5xx"}`)
+	byHost := call(`{"host":"127.0.0.1:8080","error_class":"timeout","count":2}`)
+	unknownHost := call(`{"host":"127.0.0.1:9999","error_class":"5xx"}`)
+	badClass := call(`{"instance_id":"a","error_class":"oops"}`)
+	missingTarget := call(`{"error_class":"5xx"}`)
+
+	// then
+	if byInstance != http.StatusNoContent || byHost != http.StatusNoContent {
+		t.Fatalf("reports: got %v, %v, want %v", byInstance, byHost, http.StatusNoContent)
+	}
+	if unknownHost != http.StatusNotFound {
+		t.Fatalf("unknown host: got %v, want %v", unknownHost, http.StatusNotFound)
+	}
+	if badClass != http.StatusBadRequest || missingTarget != http.StatusBadRequest {
+		t.Fatalf("bad requests: got %v, %v, want %v", badClass, missingTarget, http.StatusBadRequest)
+	}
+}
+
+func Test_OutlierDetector_ForgetsRemovedInstances(t *testing.T) {
+	// given
+	store := NewStore()
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	detector := NewOutlierDetector(store, DefaultOutlierPolicy())
+	if err := detector.Report("one", "a", ServerError, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	store.Remove("one", "a")
+	detector.sweep()
+
+	// then
+	if len(detector.stats) != 0 {
+		t.Fatalf("stats: got %+v, want none", detector.stats)
+	}
+}
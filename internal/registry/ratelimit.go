@@ -0,0 +1,222 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	rateLimitSweepInterval = time.Minute
+
+	// maxServiceIDBodySize bounds how much of a legacy request body is read to
+	// find its service ID, those bodies only hold an instance.
+	maxServiceIDBodySize = 64 << 10
+)
+
+type Rate struct {
+	PerSecond float64
+	Burst     int
+}
+
+func (r Rate) enabled() bool {
+	return r.PerSecond > 0 && r.Burst > 0
+}
+
+type RateLimits struct {
+	MutationsPerClient  Rate
+	MutationsPerService Rate
+	ReadsPerClient      Rate
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type limiter struct {
+	rate      Rate
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+	lock      sync.Mutex
+}
+
+func (l *limiter) allow(key string) (bool, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.rate.Burst), last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = l.refill(bucket, now)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / l.rate.PerSecond * float64(time.Second))
+		return false, wait
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+func (l *limiter) refill(bucket *tokenBucket, now time.Time) float64 {
+	elapsed := now.Sub(bucket.last).Seconds()
+	return math.Min(float64(l.rate.Burst), bucket.tokens+elapsed*l.rate.PerSecond)
+}
+
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		if l.refill(bucket, now) >= float64(l.rate.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func newLimiter(rate Rate) *limiter {
+	if !rate.enabled() {
+		return nil
+	}
+	return &limiter{
+		rate:    rate,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+		lock:    sync.Mutex{},
+	}
+}
+
+type rateLimiters struct {
+	mutationsPerClient  *limiter
+	mutationsPerService *limiter
+	readsPerClient      *limiter
+}
+
+func newRateLimiters(limits RateLimits) *rateLimiters {
+	return &rateLimiters{
+		mutationsPerClient:  newLimiter(limits.MutationsPerClient),
+		mutationsPerService: newLimiter(limits.MutationsPerService),
+		readsPerClient:      newLimiter(limits.ReadsPerClient),
+	}
+}
+
+type RateLimiter struct {
+	limiters atomic.Pointer[rateLimiters]
+}
+
+// Update swaps in the new limits, clients start over with a full bucket.
+func (r *RateLimiter) Update(limits RateLimits) {
+	r.limiters.Store(newRateLimiters(limits))
+}
+
+func (r *RateLimiter) mutation(next http.Handler) http.Handler {
+	return rateLimitedHandler{next: next, limiters: func() (*limiter, *limiter) {
+		limiters := r.limiters.Load()
+		return limiters.mutationsPerClient, limiters.mutationsPerService
+	}}
+}
+
+// clientMutation limits per client only, for mutations of key/value entries
+// and locks, which belong to no service.
+func (r *RateLimiter) clientMutation(next http.Handler) http.Handler {
+	return rateLimitedHandler{next: next, limiters: func() (*limiter, *limiter) {
+		return r.limiters.Load().mutationsPerClient, nil
+	}}
+}
+
+func (r *RateLimiter) read(next http.Handler) http.Handler {
+	return rateLimitedHandler{next: next, limiters: func() (*limiter, *limiter) {
+		return r.limiters.Load().readsPerClient, nil
+	}}
+}
+
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	r := &RateLimiter{}
+	r.Update(limits)
+	return r
+}
+
+type rateLimitedHandler struct {
+	next     http.Handler
+	limiters func() (perClient *limiter, perService *limiter)
+}
+
+func (h rateLimitedHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	perClient, perService := h.limiters()
+	if perClient != nil {
+		if ok, retryAfter := perClient.allow(clientIP(request)); !ok {
+			writeTooManyRequests(writer, retryAfter)
+			return
+		}
+	}
+	if perService != nil {
+		serviceID, err := requestServiceID(writer, request)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if serviceID != "" {
+			if ok, retryAfter := perService.allow(serviceID); !ok {
+				writeTooManyRequests(writer, retryAfter)
+				return
+			}
+		}
+	}
+	h.next.ServeHTTP(writer, request)
+}
+
+func writeTooManyRequests(writer http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	writer.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	http.Error(writer, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
+
+func clientIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+func requestServiceID(writer http.ResponseWriter, request *http.Request) (string, error) {
+	if serviceID := request.PathValue("serviceID"); serviceID != "" {
+		return serviceID, nil
+	}
+
+	// The legacy endpoints carry the service ID in the body, which has to be
+	// handed on to the wrapped handler intact.
+	body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, maxServiceIDBodySize))
+	if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+		return "", err
+	}
+	if err != nil {
+		return "", nil
+	}
+	request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var serviceReq struct {
+		ServiceID string `json:"service_id"`
+	}
+	if err = json.Unmarshal(body, &serviceReq); err != nil {
+		return "", nil
+	}
+	return serviceReq.ServiceID, nil
+}
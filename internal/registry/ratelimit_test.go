@@ -0,0 +1,200 @@
+package registry
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Limiter_RefillsTokens(t *testing.T) {
+	// given
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newLimiter(Rate{PerSecond: 2, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	// when
+	first, _ := l.allow("a")
+	second, _ := l.allow("a")
+	third, retryAfter := l.allow("a")
+	other, _ := l.allow("b")
+	now = now.Add(500 * time.Millisecond)
+	afterRefill, _ := l.allow("a")
+
+	// then
+	if !first || !second {
+		t.Fatalf("burst: got %v, %v, want both allowed", first, second)
+	}
+	if third {
+		t.Fatal("third request within burst window was allowed")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Fatalf("retry after: got %v, want %v", retryAfter, 500*time.Millisecond)
+	}
+	if !other {
+		t.Fatal("request for other key was throttled")
+	}
+	if !afterRefill {
+		t.Fatal("request after refill was throttled")
+	}
+}
+
+func Test_RateLimits_ThrottleMutationsPerService(t *testing.T) {
+	// given
+	limitedHandler := NewHandler(NewStore(), WithRateLimits(RateLimits{
+		MutationsPerService: Rate{PerSecond: 0.001, Burst: 1},
+		ReadsPerClient:      Rate{PerSecond: 0.001, Burst: 2},
+	}))
+
+	call := func(method string, target string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		limitedHandler.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return recorder
+	}
+
+	// when
+	respFirst := call(http.MethodPost, "/service-id/register", `{"service_id":"one","host":"127.0.0.1:8080"}`)
+	respSecond := call(http.MethodPost, "/service-id/register", `{"service_id":"one","host":"127.0.0.1:8081"}`)
+	respOther := call(http.MethodPut, "/v2/services/two/instances/a", `{"host":"127.0.0.1:8082"}`)
+	respReads := []*httptest.ResponseRecorder{
+		call(http.MethodGet, "/v2/services", ""),
+		call(http.MethodGet, "/v2/services", ""),
+		call(http.MethodGet, "/v2/services", ""),
+	}
+
+	// then
+	if respFirst.Code != http.StatusCreated {
+		t.Fatalf("status code: got %v, want %v", respFirst.Code, http.StatusCreated)
+	}
+	if respSecond.Code != http.StatusTooManyRequests {
+		t.Fatalf("status code: got %v, want %v", respSecond.Code, http.StatusTooManyRequests)
+	}
+	if respSecond.Header().Get("Retry-After") == "" {
+		t.Fatal("throttled response has no Retry-After header")
+	}
+	if respOther.Code != http.StatusCreated {
+		t.Fatalf("status code: got %v, want %v", respOther.Code, http.StatusCreated)
+	}
+	if respReads[1].Code != http.StatusOK || respReads[2].Code != http.StatusTooManyRequests {
+		t.Fatalf("reads: got %v, %v, want %v, %v", respReads[1].Code, respReads[2].Code, http.StatusOK, http.StatusTooManyRequests)
+	}
+}
+
+func Test_RateLimits_RejectOversizedLegacyBody(t *testing.T) {
+	// given
+	limitedHandler := NewHandler(NewStore(), WithRateLimits(RateLimits{
+		MutationsPerService: Rate{PerSecond: 100, Burst: 100},
+	}))
+	oversized := `{"service_id":"one","host":"127.0.0.1:8080","metadata":{"pad":"` + strings.Repeat("x", maxServiceIDBodySize) + `"}}`
+
+	// when
+	respOversized := httptest.NewRecorder()
+	limitedHandler.ServeHTTP(respOversized, httptest.NewRequest(http.MethodPost, "/service-id/register", strings.NewReader(oversized)))
+	respSmall := httptest.NewRecorder()
+	limitedHandler.ServeHTTP(respSmall, httptest.NewRequest(http.MethodPost, "/service-id/register", strings.NewReader(`{"service_id":"one","host":"127.0.0.1:8080"}`)))
+
+	// then
+	if respOversized.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized body: got %v, want %v", respOversized.Code, http.StatusRequestEntityTooLarge)
+	}
+	if respSmall.Code != http.StatusCreated {
+		t.Fatalf("small body: got %v, want %v", respSmall.Code, http.StatusCreated)
+	}
+}
+
+func Test_RateLimits_SkipPerServiceLimitForKVAndLocks(t *testing.T) {
+	// given
+	store := NewStore()
+	limitedHandler := NewHandler(store, WithRateLimits(RateLimits{
+		MutationsPerService: Rate{PerSecond: 0.001, Burst: 1},
+	}))
+	if _, _, err := store.register("jobs", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(method string, target string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		limitedHandler.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return recorder
+	}
+
+	// when
+	largeValue := call(http.MethodPut, "/v2/kv/config/blob", strings.Repeat("x", 100<<10))
+	serviceValue := call(http.MethodPut, "/v2/kv/config/owner", `{"service_id":"jobs"}`)
+	acquired := call(http.MethodPut, "/v2/locks/leader", `{"service_id":"jobs","instance_id":"a"}`)
+	released := call(http.MethodDelete, "/v2/locks/leader", `{"service_id":"jobs","instance_id":"a"}`)
+	registered := call(http.MethodPost, "/service-id/register", `{"service_id":"jobs","host":"127.0.0.1:8081"}`)
+
+	// then
+	if largeValue.Code != http.StatusOK || serviceValue.Code != http.StatusOK {
+		t.Fatalf("kv puts: got %v, %v, want %v", largeValue.Code, serviceValue.Code, http.StatusOK)
+	}
+	if acquired.Code != http.StatusOK || released.Code != http.StatusNoContent {
+		t.Fatalf("locks: got %v, %v, want %v, %v", acquired.Code, released.Code, http.StatusOK, http.StatusNoContent)
+	}
+	if registered.Code != http.StatusCreated {
+		t.Fatalf("register: got %v, want %v", registered.Code, http.StatusCreated)
+	}
+}
+
+func Test_StoreLimits_CapServicesAndInstances(t *testing.T) {
+	// given
+	store := NewStore(WithStoreLimits(StoreLimits{MaxServices: 2, MaxInstancesPerService: 2}))
+	register := func(serviceID string, instanceID string) error {
+		_, _, err := store.register(serviceID, Instance{ID: instanceID, Host: "127.0.0.1:8080"}, "", systemActor)
+		return err
+	}
+
+	// when
+	errs := []error{
+		register("one", "a"),
+		register("one", "b"),
+		register("one", "b"),
+		register("two", "a"),
+	}
+	errInstances := register("one", "c")
+	errServices := register("three", "a")
+	store.Remove("two", "a")
+	errAfterRemove := register("three", "a")
+
+	// then
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("register %d: %v", i, err)
+		}
+	}
+	if !errors.Is(errInstances, ErrLimitExceeded) {
+		t.Fatalf("instance cap: got %v, want %v", errInstances, ErrLimitExceeded)
+	}
+	if !errors.Is(errServices, ErrLimitExceeded) {
+		t.Fatalf("service cap: got %v, want %v", errServices, ErrLimitExceeded)
+	}
+	if errAfterRemove != nil {
+		t.Fatalf("register after remove: %v", errAfterRemove)
+	}
+}
+
+func Test_StoreLimits_RespondForbidden(t *testing.T) {
+	// given
+	limitedHandler := NewHandler(NewStore(WithStoreLimits(StoreLimits{MaxInstancesPerService: 1})))
+
+	call := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		limitedHandler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, target, strings.NewReader(`{"host":"127.0.0.1:8080"}`)))
+		return recorder
+	}
+
+	// when
+	first := call("/v2/services/one/instances/a")
+	second := call("/v2/services/one/instances/b")
+
+	// then
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first: got %v, want %v", first.Code, http.StatusCreated)
+	}
+	if second.Code != http.StatusForbidden || !strings.Contains(second.Body.String(), "at most 1 instances of one") {
+		t.Fatalf("second: got %v %q, want %v with the exceeded limit", second.Code, second.Body.String(), http.StatusForbidden)
+	}
+}
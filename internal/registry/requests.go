@@ -1,11 +1,60 @@
 package registry
 
 type RegisterHostRequest struct {
-	ServiceID string `json:"service_id"`
-	Host      string `json:"host"`
+	ServiceID  string            `json:"service_id"`
+	InstanceID string            `json:"instance_id,omitempty"`
+	Host       string            `json:"host"`
+	Zone       string            `json:"zone,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
 }
 
 type RemoveHostRequest struct {
-	ServiceID string `json:"service_id"`
-	Host      string `json:"host"`
+	ServiceID  string `json:"service_id"`
+	InstanceID string `json:"instance_id,omitempty"`
+	Host       string `json:"host"`
+}
+
+type RegisterInstanceRequest struct {
+	InstanceID    string            `json:"instance_id,omitempty"`
+	Host          string            `json:"host"`
+	Zone          string            `json:"zone,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	Weight        *int              `json:"weight,omitempty"`
+	ReportsHealth bool              `json:"reports_health,omitempty"`
+}
+
+type ReportCallRequest struct {
+	InstanceID string     `json:"instance_id,omitempty"`
+	Host       string     `json:"host,omitempty"`
+	ErrorClass ErrorClass `json:"error_class,omitempty"`
+	Count      int        `json:"count,omitempty"`
+}
+
+type DrainRequest struct {
+	Period string `json:"period,omitempty"`
+}
+
+type SetWeightRequest struct {
+	Weight int `json:"weight"`
+}
+
+type SetOverrideRequest struct {
+	Status Status `json:"status"`
+	TTL    string `json:"ttl,omitempty"`
+}
+
+func (r RegisterInstanceRequest) instance(instanceID string, host string) Instance {
+	return Instance{
+		ID:            instanceID,
+		Host:          host,
+		Zone:          r.Zone,
+		Metadata:      r.Metadata,
+		Weight:        r.Weight,
+		ReportsHealth: r.ReportsHealth,
+	}
+}
+
+type LockRequest struct {
+	ServiceID  string `json:"service_id"`
+	InstanceID string `json:"instance_id"`
 }
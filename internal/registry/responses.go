@@ -2,4 +2,53 @@ package registry
 
 type GetHostStatusesResponse struct {
 	HostStatuses []HostStatus `json:"host_statuses"`
+	Region       string       `json:"region,omitempty"`
+}
+
+type RegisterHostResponse struct {
+	InstanceID string `json:"instance_id"`
+}
+
+type GetServicesResponse struct {
+	Services map[string][]HostStatus `json:"services"`
+}
+
+type RestoreResponse struct {
+	Services  int `json:"services"`
+	Instances int `json:"instances"`
+	Keys      int `json:"keys"`
+}
+
+type AppsResponse struct {
+	Version  uint64                  `json:"version"`
+	Hash     string                  `json:"hash"`
+	Services map[string][]HostStatus `json:"services"`
+}
+
+type DeltaResponse struct {
+	Version     uint64  `json:"version"`
+	Hash        string  `json:"hash,omitempty"`
+	FullRefetch bool    `json:"full_refetch,omitempty"`
+	Changes     []Event `json:"changes"`
+}
+
+type GraphResponse struct {
+	Nodes        []string `json:"nodes"`
+	Edges        []Edge   `json:"edges"`
+	EvictedEdges uint64   `json:"evicted_edges"`
+}
+
+type ConsumersResponse struct {
+	ServiceID string `json:"service_id"`
+	Consumers []Edge `json:"consumers"`
+}
+
+type KVResponse struct {
+	Index   uint64    `json:"index,omitempty"`
+	Entries []KVEntry `json:"entries"`
+	Region  string    `json:"region,omitempty"`
+}
+
+type LocksResponse struct {
+	Locks []Lock `json:"locks"`
 }
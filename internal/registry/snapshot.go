@@ -0,0 +1,107 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const SnapshotVersion = 1
+
+var (
+	ErrUnsupportedSnapshot = errors.New("unsupported snapshot")
+	ErrInvalidRestoreMode  = errors.New("invalid restore mode")
+)
+
+type Snapshot struct {
+	Version  int                   `json:"version"`
+	TakenAt  time.Time             `json:"taken_at"`
+	Services map[string][]Instance `json:"services"`
+	KV       []KVEntry             `json:"kv,omitempty"`
+}
+
+type RestoreMode string
+
+const (
+	Replace RestoreMode = "replace"
+	Merge   RestoreMode = "merge"
+)
+
+func (s *Store) Snapshot() Snapshot {
+	unlock := s.rLockAll()
+	defer unlock()
+
+	snapshot := Snapshot{
+		Version:  SnapshotVersion,
+		TakenAt:  s.now(),
+		Services: make(map[string][]Instance),
+	}
+	for _, sh := range s.shards {
+		copyInstances(snapshot.Services, sh.serviceIDToInstances)
+	}
+	snapshot.KV = s.kv.snapshot()
+
+	return snapshot
+}
+
+func (s *Store) Restore(snapshot Snapshot, mode RestoreMode) error {
+	return s.restore(snapshot, mode, systemActor)
+}
+
+func (s *Store) restore(snapshot Snapshot, mode RestoreMode, actor Actor) error {
+	if snapshot.Version != SnapshotVersion {
+		return fmt.Errorf("%w: version %d", ErrUnsupportedSnapshot, snapshot.Version)
+	}
+	if mode != Replace && mode != Merge {
+		return fmt.Errorf("%w: %q", ErrInvalidRestoreMode, mode)
+	}
+
+	restored := make(map[string]map[string]Instance, len(snapshot.Services))
+	for serviceID, instances := range snapshot.Services {
+		for _, instance := range instances {
+			if instance.ID == "" {
+				return fmt.Errorf("%w: instance of %s without id", ErrUnsupportedSnapshot, serviceID)
+			}
+			if !instance.Status.valid() {
+				return fmt.Errorf("%w: %s/%s: %w", ErrUnsupportedSnapshot, serviceID, instance.ID, ErrInvalidStatus)
+			}
+			if _, ok := restored[serviceID]; !ok {
+				restored[serviceID] = make(map[string]Instance, len(instances))
+			}
+			restored[serviceID][instance.ID] = instance
+		}
+	}
+
+	for _, entry := range snapshot.KV {
+		if err := checkKey(entry.Key); err != nil {
+			return fmt.Errorf("%w: %w", ErrUnsupportedSnapshot, err)
+		}
+	}
+
+	s.restoring.Add(1)
+	defer s.restoring.Add(-1)
+
+	unlock := s.lockAll()
+	defer unlock()
+
+	if mode == Replace {
+		for _, sh := range s.shards {
+			for serviceID, instances := range sh.serviceIDToInstances {
+				for instanceID := range instances {
+					if _, ok := restored[serviceID][instanceID]; !ok {
+						s.delete(sh, serviceID, instanceID, actor)
+					}
+				}
+			}
+		}
+	}
+	for serviceID, instances := range restored {
+		sh := s.shard(serviceID)
+		for _, instance := range instances {
+			s.put(sh, serviceID, instance, actor)
+		}
+	}
+	s.kv.restore(snapshot.KV, mode)
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package registry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_Snapshot_GzipRoundTripReplace(t *testing.T) {
+	// given
+	source := NewStore()
+	if _, _, err := source.register("one", Instance{ID: "a", Host: "127.0.0.1:8080", Metadata: map[string]string{"v": "1"}}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	source.Put("one", "a", Healthy)
+
+	target := NewStore()
+	if _, _, err := target.register("two", Instance{ID: "b", Host: "127.0.0.1:8081"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	snapshotResp := httptest.NewRecorder()
+	NewHandler(source).ServeHTTP(snapshotResp, httptest.NewRequest(http.MethodGet, "/admin/snapshot?format=gzip", nil))
+
+	restoreResp := httptest.NewRecorder()
+	NewHandler(target).ServeHTTP(restoreResp, httptest.NewRequest(http.MethodPost, "/admin/restore?mode=replace", snapshotResp.Body))
+
+	// then
+	if snapshotResp.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v", snapshotResp.Code, http.StatusOK)
+	}
+	if restoreResp.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v: %s", restoreResp.Code, http.StatusOK, restoreResp.Body)
+	}
+
+	if _, ok := target.Find("two", "b"); ok {
+		t.Fatalf("two/b survived a replace restore")
+	}
+	got, ok := target.Find("one", "a")
+	if !ok {
+		t.Fatalf("one/a not restored")
+	}
+	if got.Health != Healthy || got.Metadata["v"] != "1" {
+		t.Fatalf("one/a: got %v, want healthy with metadata v=1", got)
+	}
+}
+
+func Test_Restore_Merge(t *testing.T) {
+	// given
+	target := NewStore()
+	if _, _, err := target.register("two", Instance{ID: "b", Host: "127.0.0.1:8081"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := Snapshot{
+		Version: SnapshotVersion,
+		Services: map[string][]Instance{
+			"one": {{ID: "a", Host: "127.0.0.1:8080", Status: Down}},
+		},
+	}
+
+	// when
+	resp := doSnapshotRequest(t, target, "/admin/restore?mode=merge", snapshot, false)
+
+	// then
+	if resp.Code != http.StatusOK {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusOK)
+	}
+	if _, ok := target.Find("two", "b"); !ok {
+		t.Fatalf("two/b was removed by a merge restore")
+	}
+	if got, ok := target.Find("one", "a"); !ok || got.Status != Down {
+		t.Fatalf("one/a: got %v, want down", got)
+	}
+}
+
+func Test_Restore_MoreInstancesThanTheChangeLogHolds(t *testing.T) {
+	// given
+	target := NewStore(WithChangeLogSize(16))
+	instances := make([]Instance, 0, 100)
+	for i := range 100 {
+		instances = append(instances, Instance{ID: fmt.Sprintf("i-%d", i), Host: "127.0.0.1:8080", Status: Healthy})
+	}
+	snapshot := Snapshot{
+		Version: SnapshotVersion,
+		Services: map[string][]Instance{
+			"one": instances[:50],
+			"two": instances[50:],
+		},
+	}
+
+	// when
+	if err := target.restore(snapshot, Merge, systemActor); err != nil {
+		t.Fatal(err)
+	}
+	afterRestore := target.changes.current()
+	target.Put("one", "i-0", Down)
+
+	// then
+	if afterRestore != 100 {
+		t.Fatalf("committed after restore: got %d, want %d", afterRestore, 100)
+	}
+	if got := target.changes.current(); got != 101 {
+		t.Fatalf("committed after put: got %d, want %d", got, 101)
+	}
+	if entries := target.AuditLog("", time.Time{}); len(entries) != 101 {
+		t.Fatalf("len(entries) = %d, want %d", len(entries), 101)
+	}
+}
+
+func Test_Restore_UnsupportedVersion(t *testing.T) {
+	// given
+	target := NewStore()
+	snapshot := Snapshot{Version: SnapshotVersion + 1}
+
+	// when
+	resp := doSnapshotRequest(t, target, "/admin/restore", snapshot, true)
+
+	// then
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("status code: got %v, want %v", resp.Code, http.StatusBadRequest)
+	}
+}
+
+func doSnapshotRequest(t *testing.T, store *Store, target string, snapshot Snapshot, gzipped bool) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	if gzipped {
+		gzipWriter := gzip.NewWriter(body)
+		if err := json.NewEncoder(gzipWriter).Encode(snapshot); err != nil {
+			t.Fatal(err)
+		}
+		if err := gzipWriter.Close(); err != nil {
+			t.Fatal(err)
+		}
+	} else if err := json.NewEncoder(body).Encode(snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	NewHandler(store).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, target, body))
+
+	return recorder
+}
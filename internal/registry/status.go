@@ -1,14 +1,124 @@
 package registry
 
+import (
+	"context"
+	"maps"
+	"time"
+)
+
+var overrideActor = Actor{Name: "override-sweeper"}
+
 type HostStatus struct {
-	Host   string `json:"host"`
-	Status Status `json:"status"`
+	InstanceID string            `json:"instance_id"`
+	Host       string            `json:"host"`
+	Zone       string            `json:"zone,omitempty"`
+	Status     Status            `json:"status"`
+	Health     Status            `json:"health"`
+	Override   *Override         `json:"override,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	LastCheck  *Check            `json:"last_check,omitempty"`
+	Ejection   *Ejection         `json:"ejection,omitempty"`
+	Drain      *Drain            `json:"drain,omitempty"`
+	Weight     int               `json:"weight"`
+
+	ReportsHealth bool `json:"reports_health,omitempty"`
+}
+
+func (hs HostStatus) equal(other HostStatus) bool {
+	return hs.InstanceID == other.InstanceID &&
+		hs.Host == other.Host &&
+		hs.Zone == other.Zone &&
+		hs.Status == other.Status &&
+		hs.Health == other.Health &&
+		hs.Weight == other.Weight &&
+		hs.ReportsHealth == other.ReportsHealth &&
+		hs.Override.equal(other.Override) &&
+		hs.Ejection.equal(other.Ejection) &&
+		hs.Drain.equal(other.Drain) &&
+		maps.Equal(hs.Metadata, other.Metadata)
+}
+
+type Override struct {
+	Status    Status    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+func (o *Override) equal(other *Override) bool {
+	if o == nil || other == nil {
+		return o == other
+	}
+	return o.Status == other.Status && o.ExpiresAt.Equal(other.ExpiresAt)
+}
+
+func (o *Override) active(now time.Time) bool {
+	return o != nil && (o.ExpiresAt.IsZero() || now.Before(o.ExpiresAt))
+}
+
+func (o *Override) expiresAt() time.Time {
+	if o == nil {
+		return time.Time{}
+	}
+	return o.ExpiresAt
+}
+
+// OverrideSweeper clears overrides once their TTL is over, so the status change
+// reaches event and delta consumers, which reads alone would not tell.
+type OverrideSweeper struct {
+	store    *Store
+	interval time.Duration
+}
+
+func (o OverrideSweeper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			o.store.expireOverrides(overrideActor)
+		}
+	}
+}
+
+func NewOverrideSweeper(store *Store, interval time.Duration) OverrideSweeper {
+	return OverrideSweeper{
+		store:    store,
+		interval: interval,
+	}
+}
+
+type Check struct {
+	Status     Status               `json:"status"`
+	CheckedAt  time.Time            `json:"checked_at"`
+	Error      string               `json:"error,omitempty"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+type Component struct {
+	Status  Status         `json:"status"`
+	Output  string         `json:"output,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
 }
 
 type Status string
 
 const (
-	Unknown Status = "unknown"
-	Healthy Status = "healthy"
-	Down    Status = "down"
+	Unknown      Status = "unknown"
+	Healthy      Status = "healthy"
+	Down         Status = "down"
+	OutOfService Status = "out_of_service"
+	Starting     Status = "starting"
+	Degraded     Status = "degraded"
+	Draining     Status = "draining"
 )
+
+func (s Status) valid() bool {
+	switch s {
+	case Unknown, Healthy, Down, OutOfService, Starting, Degraded:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,92 +1,788 @@
 package registry
 
 import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+var (
+	ErrNotFound           = errors.New("instance not found")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrInvalidStatus      = errors.New("invalid status")
+	ErrLimitExceeded      = errors.New("registry limit exceeded")
+)
+
+const defaultShardCount = 64
+
 type Store struct {
-	serviceIDToHostStatuses map[string]map[string]Status
-	lock                    sync.RWMutex
+	namespace   string
+	shards      []*shard
+	now         func() time.Time
+	subscribers *subscribers
+	changes     *changeLog
+	audit       *auditLog
+	kv          *KV
+	locks       *locks
+	limits      StoreLimits
+	services    atomic.Int64
+	restoring   atomic.Int32
+}
+
+type StoreLimits struct {
+	MaxServices            int
+	MaxInstancesPerService int
+}
+
+type shard struct {
+	serviceIDToInstances map[string]map[string]Instance
+	serviceIDToVersion   map[string]uint64
+	pending              []Event
+	lock                 sync.RWMutex
 }
 
-func (s *Store) addNew(serviceID string, host string) {
-	s.Put(serviceID, host, Unknown)
+func (s *Store) shard(serviceID string) *shard {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(serviceID))
+	return s.shards[hash.Sum32()%uint32(len(s.shards))]
 }
 
-func (s *Store) Put(serviceID string, host string, status Status) {
-	s.lock.Lock()
-	defer s.lock.Unlock()
+func (s *Store) register(serviceID string, instance Instance, ifMatch string, actor Actor) (HostStatus, bool, error) {
+	if err := checkWeight(instance.Weight); err != nil {
+		return HostStatus{}, false, err
+	}
+
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
 
-	hostStatuses, ok := s.serviceIDToHostStatuses[serviceID]
+	current, ok := sh.serviceIDToInstances[serviceID][instance.ID]
+	if err := checkIfMatch(ifMatch, current.hostStatus(s.now()), ok); err != nil {
+		return HostStatus{}, false, err
+	}
 	if !ok {
-		hostStatuses = make(map[string]Status)
-		s.serviceIDToHostStatuses[serviceID] = hostStatuses
+		if err := s.checkLimits(sh, serviceID); err != nil {
+			return HostStatus{}, false, err
+		}
+	}
+
+	if ok && current.Host == instance.Host {
+		instance.Status = current.Status
+		instance.LastCheck = current.LastCheck
+		instance.Ejection = current.Ejection
+		instance.Drain = current.Drain
+	} else {
+		instance.Status = Unknown
+		instance.LastCheck = nil
+	}
+	instance.Override = current.Override
+	if instance.ReportsHealth {
+		instance.ReportedAt = s.now()
 	}
+	if instance.Weight == nil {
+		instance.Weight = current.Weight
+	}
+
+	s.put(sh, serviceID, instance, actor)
 
-	hostStatuses[host] = status
+	return instance.hostStatus(s.now()), !ok, nil
 }
 
-func (s *Store) Remove(serviceID string, host string) bool {
-	s.lock.Lock()
-	defer s.lock.Unlock()
+func (s *Store) Put(serviceID string, instanceID string, status Status) {
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
 
-	ips, ok := s.serviceIDToHostStatuses[serviceID]
+	instance, ok := sh.serviceIDToInstances[serviceID][instanceID]
 	if !ok {
-		return false
+		return
+	}
+
+	instance.Status = status
+	s.put(sh, serviceID, instance, systemActor)
+}
+
+func (s *Store) PutCheck(serviceID string, instanceID string, check Check) {
+	_, _ = s.reportCheck(serviceID, instanceID, check, false, checkerActor)
+}
+
+// reportCheck stores the result of a health check, pushed tells that the
+// instance reported it itself rather than being probed.
+func (s *Store) reportCheck(serviceID string, instanceID string, check Check, pushed bool, actor Actor) (HostStatus, error) {
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
+
+	instance, ok := sh.serviceIDToInstances[serviceID][instanceID]
+	if !ok {
+		return HostStatus{}, ErrNotFound
+	}
+
+	// A check that failed to reach the instance marks it down, whatever status
+	// was reported along with the error.
+	if check.Error != "" {
+		check.Status = Down
+	}
+	instance.Status = check.Status
+	instance.LastCheck = &check
+	if pushed {
+		instance.ReportedAt = s.now()
+	}
+	s.put(sh, serviceID, instance, actor)
+
+	return instance.hostStatus(s.now()), nil
+}
+
+func (s *Store) setOverride(serviceID string, instanceID string, override Override, actor Actor) (HostStatus, error) {
+	if !override.Status.valid() {
+		return HostStatus{}, ErrInvalidStatus
+	}
+
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
+
+	instance, ok := sh.serviceIDToInstances[serviceID][instanceID]
+	if !ok {
+		return HostStatus{}, ErrNotFound
+	}
+
+	instance.Override = &override
+	s.put(sh, serviceID, instance, actor)
+
+	return instance.hostStatus(s.now()), nil
+}
+
+func (s *Store) clearOverride(serviceID string, instanceID string, actor Actor) (HostStatus, error) {
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
+
+	instance, ok := sh.serviceIDToInstances[serviceID][instanceID]
+	if !ok {
+		return HostStatus{}, ErrNotFound
 	}
 
-	if _, ok = ips[host]; ok {
-		delete(ips, host)
+	instance.Override = nil
+	s.put(sh, serviceID, instance, actor)
+
+	return instance.hostStatus(s.now()), nil
+}
+
+func (s *Store) setWeight(serviceID string, instanceID string, weight int, actor Actor) (HostStatus, error) {
+	if err := checkWeight(&weight); err != nil {
+		return HostStatus{}, err
+	}
 
-		if len(ips) == 0 {
-			delete(s.serviceIDToHostStatuses, serviceID)
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
+
+	instance, ok := sh.serviceIDToInstances[serviceID][instanceID]
+	if !ok {
+		return HostStatus{}, ErrNotFound
+	}
+
+	instance.Weight = &weight
+	s.put(sh, serviceID, instance, actor)
+
+	return instance.hostStatus(s.now()), nil
+}
+
+func (s *Store) setVersionWeight(serviceID string, version string, weight int, actor Actor) ([]HostStatus, error) {
+	if err := checkWeight(&weight); err != nil {
+		return nil, err
+	}
+
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
+
+	now := s.now()
+	var result []HostStatus
+	for _, instance := range sh.serviceIDToInstances[serviceID] {
+		if instance.Metadata[versionMetadataKey] != version {
+			continue
 		}
+		instance.Weight = &weight
+		s.put(sh, serviceID, instance, actor)
+		result = append(result, instance.hostStatus(now))
+	}
+	if len(result) == 0 {
+		return nil, ErrNotFound
+	}
+	rankByZone(result, "")
+
+	return result, nil
+}
+
+func (s *Store) eject(serviceID string, instanceID string, ejection Ejection, maxPercent int) (bool, error) {
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
 
+	instances := sh.serviceIDToInstances[serviceID]
+	instance, ok := instances[instanceID]
+	if !ok {
+		return false, ErrNotFound
+	}
+
+	now := s.now()
+	if instance.Ejection.active(now) {
+		return false, nil
+	}
+
+	ejected := 0
+	for _, other := range instances {
+		if other.Ejection.active(now) {
+			ejected++
+		}
+	}
+	// As in Envoy, one instance may always be ejected from a service with peers.
+	allowed := len(instances) * maxPercent / 100
+	if allowed < 1 && len(instances) > 1 {
+		allowed = 1
+	}
+	if ejected >= allowed {
+		return false, nil
+	}
+
+	instance.Ejection = &ejection
+	s.put(sh, serviceID, instance, outlierActor)
+
+	return true, nil
+}
+
+func (s *Store) drain(serviceID string, instanceID string, drain Drain, actor Actor) (HostStatus, error) {
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
+
+	instance, ok := sh.serviceIDToInstances[serviceID][instanceID]
+	if !ok {
+		return HostStatus{}, ErrNotFound
+	}
+
+	instance.Drain = &drain
+	s.put(sh, serviceID, instance, actor)
+
+	return instance.hostStatus(s.now()), nil
+}
+
+func (s *Store) undrain(serviceID string, instanceID string, actor Actor) (HostStatus, error) {
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
+
+	instance, ok := sh.serviceIDToInstances[serviceID][instanceID]
+	if !ok {
+		return HostStatus{}, ErrNotFound
+	}
+
+	instance.Drain = nil
+	s.put(sh, serviceID, instance, actor)
+
+	return instance.hostStatus(s.now()), nil
+}
+
+func (s *Store) removeDrained(actor Actor) int {
+	removed := 0
+	for _, sh := range s.shards {
+		sh.lock.Lock()
+		now := s.now()
+		for serviceID, instances := range sh.serviceIDToInstances {
+			for instanceID, instance := range instances {
+				if instance.Drain.expired(now) {
+					s.delete(sh, serviceID, instanceID, actor)
+					removed++
+				}
+			}
+		}
+		s.unlock(sh)
+	}
+	return removed
+}
+
+// expireOverrides clears the overrides whose TTL ran out.
+func (s *Store) expireOverrides(actor Actor) int {
+	return s.sweepLapsed(func(instance *Instance, now time.Time) (time.Time, bool) {
+		if instance.Override == nil || instance.Override.active(now) {
+			return time.Time{}, false
+		}
+		expiresAt := instance.Override.ExpiresAt
+		instance.Override = nil
+		return expiresAt, true
+	}, actor)
+}
+
+// expireEjections puts back the instances whose ejection is over.
+func (s *Store) expireEjections(actor Actor) int {
+	return s.sweepLapsed(func(instance *Instance, now time.Time) (time.Time, bool) {
+		if instance.Ejection == nil || instance.Ejection.active(now) {
+			return time.Time{}, false
+		}
+		until := instance.Ejection.Until
+		instance.Ejection = nil
+		return until, true
+	}, actor)
+}
+
+// sweepLapsed stores every instance lapse changed, e.g. by clearing a timed
+// override that ran out. Reading an instance already ignores what lapsed, so
+// the event compares it with what was served until it lapsed.
+func (s *Store) sweepLapsed(lapse func(instance *Instance, now time.Time) (time.Time, bool), actor Actor) int {
+	lapsed := 0
+	for _, sh := range s.shards {
+		sh.lock.Lock()
+		now := s.now()
+		for serviceID, instances := range sh.serviceIDToInstances {
+			for instanceID, instance := range instances {
+				before := instance
+				lapsedAt, ok := lapse(&instance, now)
+				if !ok {
+					continue
+				}
+				previous := before.hostStatus(lapsedAt.Add(-time.Nanosecond))
+				instances[instanceID] = instance
+				next := instance.hostStatus(now)
+				s.emit(sh, serviceID, &previous, &next, now, actor)
+				lapsed++
+			}
+		}
+		s.unlock(sh)
+	}
+	return lapsed
+}
+
+func (s *Store) Remove(serviceID string, instanceID string) bool {
+	return s.remove(serviceID, instanceID, systemActor)
+}
+
+func (s *Store) remove(serviceID string, instanceID string, actor Actor) bool {
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
+
+	instances, ok := sh.serviceIDToInstances[serviceID]
+	if !ok {
+		return false
+	}
+
+	if _, ok = instances[instanceID]; ok {
+		s.delete(sh, serviceID, instanceID, actor)
 		return true
 	}
 	return false
 }
 
+func (s *Store) removeHost(serviceID string, host string, actor Actor) bool {
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
+
+	removed := false
+	for instanceID, instance := range sh.serviceIDToInstances[serviceID] {
+		if instance.Host == host {
+			s.delete(sh, serviceID, instanceID, actor)
+			removed = true
+		}
+	}
+
+	return removed
+}
+
+func (s *Store) removeIfMatch(serviceID string, instanceID string, ifMatch string, actor Actor) error {
+	sh := s.shard(serviceID)
+	sh.lock.Lock()
+	defer s.unlock(sh)
+
+	instance, ok := sh.serviceIDToInstances[serviceID][instanceID]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := checkIfMatch(ifMatch, instance.hostStatus(s.now()), ok); err != nil {
+		return err
+	}
+
+	s.delete(sh, serviceID, instanceID, actor)
+
+	return nil
+}
+
+func (s *Store) checkLimits(sh *shard, serviceID string) error {
+	instances, ok := sh.serviceIDToInstances[serviceID]
+	if !ok {
+		// Services live in different shards, so concurrent registrations of new
+		// services may overshoot MaxServices by at most the number of shards.
+		if s.limits.MaxServices > 0 && s.services.Load() >= int64(s.limits.MaxServices) {
+			return fmt.Errorf("%w: at most %d services", ErrLimitExceeded, s.limits.MaxServices)
+		}
+		return nil
+	}
+	if s.limits.MaxInstancesPerService > 0 && len(instances) >= s.limits.MaxInstancesPerService {
+		return fmt.Errorf("%w: at most %d instances of %s", ErrLimitExceeded, s.limits.MaxInstancesPerService, serviceID)
+	}
+	return nil
+}
+
+func (s *Store) put(sh *shard, serviceID string, instance Instance, actor Actor) {
+	instances, ok := sh.serviceIDToInstances[serviceID]
+	if !ok {
+		instances = make(map[string]Instance)
+		sh.serviceIDToInstances[serviceID] = instances
+		s.services.Add(1)
+	}
+
+	now := s.now()
+	var previous *HostStatus
+	if current, ok := instances[instance.ID]; ok {
+		hostStatus := current.hostStatus(now)
+		previous = &hostStatus
+	}
+	instances[instance.ID] = instance
+
+	next := instance.hostStatus(now)
+	s.emit(sh, serviceID, previous, &next, now, actor)
+}
+
+func (s *Store) delete(sh *shard, serviceID string, instanceID string, actor Actor) {
+	instances := sh.serviceIDToInstances[serviceID]
+	instance := instances[instanceID]
+	delete(instances, instanceID)
+
+	if len(instances) == 0 {
+		delete(sh.serviceIDToInstances, serviceID)
+		s.services.Add(-1)
+	}
+
+	now := s.now()
+	previous := instance.hostStatus(now)
+	s.emit(sh, serviceID, &previous, nil, now, actor)
+}
+
+func (s *Store) emit(sh *shard, serviceID string, previous *HostStatus, next *HostStatus, now time.Time, actor Actor) {
+	if event, ok := newEvent(serviceID, previous, next, now); ok {
+		event.Actor = actor.Name
+		event.ClaimedActor = actor.Claimed
+		event.Namespace = s.namespace
+		event.Version = s.changes.reserve()
+		sh.serviceIDToVersion[serviceID] = event.Version
+		sh.pending = append(sh.pending, event)
+	}
+}
+
+// unlock releases a write-locked shard and only then hands its events to the
+// change log, so releasing locks, publishing and auditing do not hold up the
+// shard.
+func (s *Store) unlock(sh *shard) {
+	pending := sh.pending
+	sh.pending = nil
+	sh.lock.Unlock()
+
+	for _, event := range pending {
+		s.changes.commit(event, s.record)
+	}
+}
+
+func (s *Store) record(event Event) {
+	s.releaseLocksOf(event)
+	s.subscribers.publish(event)
+	s.audit.append(event)
+}
+
+// auditKV records a key/value change, which has no event of its own.
+func (s *Store) auditKV(action AuditAction, key string, actor Actor) {
+	s.audit.add(AuditEntry{
+		Time:         s.now(),
+		Actor:        actor.Name,
+		ClaimedActor: actor.Claimed,
+		Action:       action,
+		Namespace:    s.namespace,
+		Key:          key,
+	})
+}
+
+// Namespace is empty for the default namespace, which keeps the original
+// unprefixed routes and event format.
+func (s *Store) Namespace() string {
+	return s.namespace
+}
+
+func (s *Store) HasNamespace(namespace string) bool {
+	if s.namespace == "" {
+		return namespace == DefaultNamespace
+	}
+	return namespace == s.namespace
+}
+
+// KV returns the key/value store kept alongside the services, which is
+// snapshotted and restored together with them.
+func (s *Store) KV() *KV {
+	return s.kv
+}
+
+func (s *Store) Ready() (bool, string) {
+	if s.restoring.Load() > 0 {
+		return false, "registry store is restoring a snapshot"
+	}
+	return true, ""
+}
+
+// Close waits for the audit entries queued for the audit writer to be written.
+func (s *Store) Close() {
+	s.audit.close()
+}
+
+func (s *Store) AuditLog(serviceID string, since time.Time) []AuditEntry {
+	return s.audit.query(serviceID, since)
+}
+
+// Subscribe passes the events that did not fit into the buffer to dropped,
+// unless it is nil.
+func (s *Store) Subscribe(buffer int, dropped func(Event)) (<-chan Event, func()) {
+	return s.subscribers.add(buffer, dropped)
+}
+
+func (s *Store) Find(serviceID string, instanceID string) (HostStatus, bool) {
+	sh := s.shard(serviceID)
+	sh.lock.RLock()
+	defer sh.lock.RUnlock()
+
+	instance, ok := sh.serviceIDToInstances[serviceID][instanceID]
+	if !ok {
+		return HostStatus{}, false
+	}
+
+	return instance.hostStatus(s.now()), true
+}
+
 func (s *Store) Get(serviceID string) []HostStatus {
-	s.lock.RLock()
-	defer s.lock.RUnlock()
+	sh := s.shard(serviceID)
+	sh.lock.RLock()
+	defer sh.lock.RUnlock()
 
-	hostStatuses, _ := s.serviceIDToHostStatuses[serviceID]
+	instances := sh.serviceIDToInstances[serviceID]
 
-	result := make([]HostStatus, 0, len(hostStatuses))
-	for ipString := range hostStatuses {
-		result = append(result, HostStatus{
-			Host:   ipString,
-			Status: hostStatuses[ipString],
-		})
+	result := make([]HostStatus, 0, len(instances))
+	for _, instance := range instances {
+		result = append(result, instance.hostStatus(s.now()))
 	}
+	rankByZone(result, "")
 
 	return result
 }
 
-func (s *Store) GetServiceIDsToHosts() map[string][]string {
-	s.lock.RLock()
-	defer s.lock.RUnlock()
+func (s *Store) getVersioned(serviceID string) ([]HostStatus, uint64) {
+	sh := s.shard(serviceID)
+	sh.lock.RLock()
+	defer sh.lock.RUnlock()
 
-	result := make(map[string][]string, len(s.serviceIDToHostStatuses))
-	for serviceID, hostStatuses := range s.serviceIDToHostStatuses {
-		result[serviceID] = make([]string, 0, len(hostStatuses))
-		for ipString := range hostStatuses {
-			result[serviceID] = append(result[serviceID], ipString)
+	instances := sh.serviceIDToInstances[serviceID]
+
+	now := s.now()
+	result := make([]HostStatus, 0, len(instances))
+	for _, instance := range instances {
+		result = append(result, instance.hostStatus(now))
+	}
+	rankByZone(result, "")
+
+	return result, sh.serviceIDToVersion[serviceID]
+}
+
+// zonedVersion returns the version of the service along with the zone, or an
+// empty zone when no instance is in it, which ranks the instances the same.
+func (s *Store) zonedVersion(serviceID string, zone string) (uint64, string) {
+	sh := s.shard(serviceID)
+	sh.lock.RLock()
+	defer sh.lock.RUnlock()
+
+	version := sh.serviceIDToVersion[serviceID]
+	for _, instance := range sh.serviceIDToInstances[serviceID] {
+		if zone != "" && instance.Zone == zone {
+			return version, zone
 		}
 	}
+	return version, ""
+}
+
+func (s *Store) serviceVersion(serviceID string) uint64 {
+	sh := s.shard(serviceID)
+	sh.lock.RLock()
+	defer sh.lock.RUnlock()
+
+	return sh.serviceIDToVersion[serviceID]
+}
+
+func (s *Store) GetAll() map[string][]HostStatus {
+	services, _ := s.GetAllVersioned()
+	return services
+}
+
+func (s *Store) GetServiceIDsToHosts() map[string][]string {
+	result := make(map[string][]string)
+	for _, sh := range s.shards {
+		sh.lock.RLock()
+		for serviceID, instances := range sh.serviceIDToInstances {
+			result[serviceID] = make([]string, 0, len(instances))
+			for _, instance := range instances {
+				result[serviceID] = append(result[serviceID], instance.Host)
+			}
+		}
+		sh.lock.RUnlock()
+	}
 
 	return result
 }
 
-func NewStore() *Store {
-	serviceIdToHostStatuses := make(map[string]map[string]Status)
-	return NewStoreFrom(serviceIdToHostStatuses)
+func (s *Store) GetServiceIDsToInstances() map[string][]Instance {
+	result := make(map[string][]Instance)
+	for _, sh := range s.shards {
+		sh.lock.RLock()
+		copyInstances(result, sh.serviceIDToInstances)
+		sh.lock.RUnlock()
+	}
+
+	return result
 }
 
-func NewStoreFrom(serviceIdToHostStatuses map[string]map[string]Status) *Store {
-	return &Store{
-		serviceIDToHostStatuses: serviceIdToHostStatuses,
-		lock:                    sync.RWMutex{},
+func (s *Store) GetAllVersioned() (map[string][]HostStatus, uint64) {
+	unlock := s.rLockAll()
+	defer unlock()
+
+	now := s.now()
+	result := make(map[string][]HostStatus)
+	for _, sh := range s.shards {
+		for serviceID, instances := range sh.serviceIDToInstances {
+			result[serviceID] = make([]HostStatus, 0, len(instances))
+			for _, instance := range instances {
+				result[serviceID] = append(result[serviceID], instance.hostStatus(now))
+			}
+		}
 	}
+
+	return result, s.changes.current()
+}
+
+func (s *Store) ChangesSince(version uint64) ([]Event, uint64, bool) {
+	return s.changes.since(version)
+}
+
+func (s *Store) rLockAll() func() {
+	for _, sh := range s.shards {
+		sh.lock.RLock()
+	}
+	return func() {
+		for _, sh := range s.shards {
+			sh.lock.RUnlock()
+		}
+	}
+}
+
+func (s *Store) lockAll() func() {
+	for _, sh := range s.shards {
+		sh.lock.Lock()
+	}
+	return func() {
+		for _, sh := range s.shards {
+			s.unlock(sh)
+		}
+	}
+}
+
+func copyInstances(dst map[string][]Instance, src map[string]map[string]Instance) {
+	for serviceID, instances := range src {
+		dst[serviceID] = make([]Instance, 0, len(instances))
+		for _, instance := range instances {
+			dst[serviceID] = append(dst[serviceID], instance)
+		}
+	}
+}
+
+type StoreOption func(*storeOptions)
+
+type storeOptions struct {
+	namespace     string
+	auditLogSize  int
+	auditOut      io.Writer
+	limits        StoreLimits
+	changeLogSize int
+}
+
+func WithNamespace(namespace string) StoreOption {
+	return func(o *storeOptions) {
+		o.namespace = namespace
+	}
+}
+
+func WithChangeLogSize(size int) StoreOption {
+	return func(o *storeOptions) {
+		o.changeLogSize = size
+	}
+}
+
+func WithStoreLimits(limits StoreLimits) StoreOption {
+	return func(o *storeOptions) {
+		o.limits = limits
+	}
+}
+
+func WithAuditLogSize(size int) StoreOption {
+	return func(o *storeOptions) {
+		o.auditLogSize = size
+	}
+}
+
+func WithAuditWriter(out io.Writer) StoreOption {
+	return func(o *storeOptions) {
+		o.auditOut = out
+	}
+}
+
+func NewStore(opts ...StoreOption) *Store {
+	serviceIDToInstances := make(map[string]map[string]Instance)
+	return NewStoreFrom(serviceIDToInstances, opts...)
+}
+
+func NewStoreFrom(serviceIDToInstances map[string]map[string]Instance, opts ...StoreOption) *Store {
+	return newStore(defaultShardCount, serviceIDToInstances, opts...)
+}
+
+func newStore(shardCount int, serviceIDToInstances map[string]map[string]Instance, opts ...StoreOption) *Store {
+	o := storeOptions{auditLogSize: defaultAuditLogSize, changeLogSize: defaultChangeLogSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	store := &Store{
+		namespace:   o.namespace,
+		shards:      make([]*shard, shardCount),
+		now:         time.Now,
+		subscribers: newSubscribers(),
+		changes:     newChangeLog(o.changeLogSize),
+		audit:       newAuditLog(o.auditLogSize, o.auditOut),
+		kv:          NewKV(),
+		locks:       newLocks(),
+		limits:      o.limits,
+	}
+	for i := range store.shards {
+		store.shards[i] = &shard{
+			serviceIDToInstances: make(map[string]map[string]Instance),
+			serviceIDToVersion:   make(map[string]uint64),
+			lock:                 sync.RWMutex{},
+		}
+	}
+	now := store.now()
+	for serviceID, instances := range serviceIDToInstances {
+		store.shard(serviceID).serviceIDToInstances[serviceID] = instances
+		store.services.Add(1)
+		for _, instance := range instances {
+			store.changes.counts[instance.hostStatus(now).Status]++
+		}
+	}
+
+	return store
 }
@@ -0,0 +1,164 @@
+package registry
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Store_ConcurrentMutations(t *testing.T) {
+	// given
+	store := NewStore()
+	serviceCount := 16
+	instanceCount := 32
+
+	// when
+	wg := &sync.WaitGroup{}
+	for service := range serviceCount {
+		serviceID := fmt.Sprintf("service-%d", service)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for instance := range instanceCount {
+				instanceID := fmt.Sprintf("instance-%d", instance)
+				if _, _, err := store.register(serviceID, Instance{ID: instanceID, Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+					t.Error(err)
+					return
+				}
+				store.Put(serviceID, instanceID, Healthy)
+				store.Get(serviceID)
+			}
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range instanceCount {
+				store.GetServiceIDsToInstances()
+				store.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// then
+	serviceIDsToInstances := store.GetServiceIDsToInstances()
+	if len(serviceIDsToInstances) != serviceCount {
+		t.Fatalf("len(serviceIDsToInstances) = %d, want %d", len(serviceIDsToInstances), serviceCount)
+	}
+	for serviceID, instances := range serviceIDsToInstances {
+		if len(instances) != instanceCount {
+			t.Fatalf("len(%s) = %d, want %d", serviceID, len(instances), instanceCount)
+		}
+		for _, instance := range instances {
+			if instance.Status != Healthy {
+				t.Fatalf("%s/%s = %s, want %s", serviceID, instance.ID, instance.Status, Healthy)
+			}
+		}
+	}
+}
+
+func Test_NewStoreFrom_DistributesServices(t *testing.T) {
+	// given
+	serviceIDToInstances := map[string]map[string]Instance{
+		"one": {"a": {ID: "a", Host: "127.0.0.1:8080", Status: Healthy}},
+		"two": {"b": {ID: "b", Host: "127.0.0.1:8081", Status: Down}},
+	}
+
+	// when
+	store := NewStoreFrom(serviceIDToInstances)
+
+	// then
+	if got, ok := store.Find("one", "a"); !ok || got.Status != Healthy {
+		t.Fatalf("one/a: got %v, want %s", got, Healthy)
+	}
+	if got, ok := store.Find("two", "b"); !ok || got.Status != Down {
+		t.Fatalf("two/b: got %v, want %s", got, Down)
+	}
+}
+
+func Benchmark_Store_Put(b *testing.B) {
+	for _, shardCount := range []int{1, defaultShardCount} {
+		b.Run(fmt.Sprintf("shards=%d", shardCount), func(b *testing.B) {
+			store, serviceIDs := newBenchmarkStore(b, shardCount)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					serviceID := serviceIDs[i%len(serviceIDs)]
+					store.Put(serviceID, "instance-0", Healthy)
+					i++
+				}
+			})
+		})
+	}
+}
+
+func Benchmark_Store_PutAndGet(b *testing.B) {
+	for _, shardCount := range []int{1, defaultShardCount} {
+		b.Run(fmt.Sprintf("shards=%d", shardCount), func(b *testing.B) {
+			store, serviceIDs := newBenchmarkStore(b, shardCount)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					serviceID := serviceIDs[i%len(serviceIDs)]
+					if i%4 == 0 {
+						store.Put(serviceID, "instance-0", Healthy)
+					} else {
+						store.Get(serviceID)
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func newBenchmarkStore(b *testing.B, shardCount int) (*Store, []string) {
+	store := newStore(shardCount, make(map[string]map[string]Instance))
+	serviceIDs := make([]string, 1024)
+	for i := range serviceIDs {
+		serviceIDs[i] = fmt.Sprintf("service-%d", i)
+		for j := range 4 {
+			instance := Instance{ID: fmt.Sprintf("instance-%d", j), Host: "127.0.0.1:8080"}
+			if _, _, err := store.register(serviceIDs[i], instance, "", systemActor); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return store, serviceIDs
+}
+
+func Test_Store_ExpireOverridesEmitsStatusChange(t *testing.T) {
+	// given
+	store := NewStore()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	if _, _, err := store.register("one", Instance{ID: "a", Host: "127.0.0.1:8080"}, "", systemActor); err != nil {
+		t.Fatal(err)
+	}
+	store.Put("one", "a", Healthy)
+	if _, err := store.setOverride("one", "a", Override{Status: OutOfService, ExpiresAt: now.Add(time.Minute)}, systemActor); err != nil {
+		t.Fatal(err)
+	}
+	events, cancel := store.Subscribe(8, nil)
+	defer cancel()
+
+	// when
+	early := store.expireOverrides(overrideActor)
+	now = now.Add(time.Minute)
+	expired := store.expireOverrides(overrideActor)
+
+	// then
+	if early != 0 || expired != 1 {
+		t.Fatalf("expired: got %d before and %d after the ttl, want 0 and 1", early, expired)
+	}
+	event := <-events
+	if event.Type != StatusChanged || event.Previous.Status != OutOfService || event.Instance.Status != Healthy || event.Actor != overrideActor.Name {
+		t.Fatalf("event: got %+v, want status change from out of service to healthy", event)
+	}
+	if instance, _ := store.Find("one", "a"); instance.Override != nil {
+		t.Fatalf("override: got %+v, want none", instance.Override)
+	}
+}
@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+var ErrInvalidWeight = errors.New("invalid weight")
+
+const (
+	DefaultWeight = 100
+	MaxWeight     = 10000
+)
+
+const (
+	strategyWeighted   = "weighted"
+	versionMetadataKey = "version"
+)
+
+func checkWeight(weight *int) error {
+	if weight != nil && (*weight < 0 || *weight > MaxWeight) {
+		return fmt.Errorf("%w: %d is not between 0 and %d", ErrInvalidWeight, *weight, MaxWeight)
+	}
+	return nil
+}
+
+func (i Instance) weight() int {
+	if i.Weight == nil {
+		return DefaultWeight
+	}
+	return *i.Weight
+}
+
+// picker narrows a lookup result down, a nil picker returns every instance.
+type picker func(hostStatuses []HostStatus) []HostStatus
+
+func (p picker) apply(hostStatuses []HostStatus) []HostStatus {
+	if p == nil {
+		return hostStatuses
+	}
+	return p(hostStatuses)
+}
+
+func lookupStrategy(query url.Values) (picker, error) {
+	strategy := query.Get("strategy")
+	if strategy == "" {
+		return nil, nil
+	}
+	if strategy != strategyWeighted {
+		return nil, fmt.Errorf("unsupported strategy %q", strategy)
+	}
+
+	count := 1
+	if rawCount := query.Get("count"); rawCount != "" {
+		parsed, err := strconv.Atoi(rawCount)
+		if err != nil || parsed < 1 {
+			return nil, fmt.Errorf("count %q is not a positive number", rawCount)
+		}
+		count = parsed
+	}
+	return func(hostStatuses []HostStatus) []HostStatus {
+		return weightedOrder(hostStatuses, count, rand.Float64)
+	}, nil
+}
+
+// weightedOrder picks up to count healthy instances by weighted random sampling
+// without replacement, using the Efraimidis-Spirakis keys u^(1/weight).
+func weightedOrder(hostStatuses []HostStatus, count int, random func() float64) []HostStatus {
+	type keyed struct {
+		hostStatus HostStatus
+		key        float64
+	}
+
+	candidates := make([]keyed, 0, len(hostStatuses))
+	for _, hostStatus := range hostStatuses {
+		if hostStatus.Status != Healthy || hostStatus.Weight <= 0 {
+			continue
+		}
+		// 1 - random() lies in (0, 1], so the logarithm stays finite.
+		key := math.Log(1-random()) / float64(hostStatus.Weight)
+		candidates = append(candidates, keyed{hostStatus: hostStatus, key: key})
+	}
+	slices.SortFunc(candidates, func(a, b keyed) int {
+		return cmp.Compare(b.key, a.key)
+	})
+
+	picked := make([]HostStatus, 0, min(count, len(candidates)))
+	for _, candidate := range candidates[:min(count, len(candidates))] {
+		picked = append(picked, candidate.hostStatus)
+	}
+	return picked
+}
@@ -0,0 +1,135 @@
+package registry
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_WeightedOrder_FollowsWeights(t *testing.T) {
+	// given
+	hostStatuses := []HostStatus{
+		{InstanceID: "stable", Status: Healthy, Weight: 90},
+		{InstanceID: "canary", Status: Healthy, Weight: 10},
+		{InstanceID: "paused", Status: Healthy, Weight: 0},
+		{InstanceID: "down", Status: Down, Weight: 100},
+	}
+	random := rand.New(rand.NewPCG(1, 2)).Float64
+
+	// when
+	picks := make(map[string]int)
+	for range 10000 {
+		picked := weightedOrder(hostStatuses, 1, random)
+		picks[picked[0].InstanceID]++
+	}
+	all := weightedOrder(hostStatuses, 10, random)
+
+	// then
+	if canary := picks["canary"]; canary < 800 || canary > 1200 {
+		t.Fatalf("canary picked %d of 10000 times, want about 1000", canary)
+	}
+	if picks["paused"] != 0 || picks["down"] != 0 {
+		t.Fatalf("picked instances without traffic: %v", picks)
+	}
+	if len(all) != 2 {
+		t.Fatalf("ordered list: got %d instances, want 2", len(all))
+	}
+}
+
+func Test_SetWeight(t *testing.T) {
+	// given
+	store := NewStore()
+	weightHandler := NewHandler(store)
+	register := func(instanceID string, version string) {
+		instance := Instance{ID: instanceID, Host: instanceID + ":8080", Metadata: map[string]string{versionMetadataKey: version}}
+		if _, _, err := store.register("one", instance, "", systemActor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	register("a", "v1")
+	register("b", "v1")
+	register("c", "v2")
+
+	call := func(path string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		weightHandler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, path, strings.NewReader(body)))
+		return recorder
+	}
+
+	// when
+	byVersion := call("/admin/services/one/versions/v2/weight", `{"weight":5}`)
+	byInstance := call("/admin/services/one/instances/a/weight", `{"weight":0}`)
+	tooHeavy := call("/admin/services/one/instances/a/weight", `{"weight":100000}`)
+	unknownVersion := call("/admin/services/one/versions/v3/weight", `{"weight":5}`)
+	register("c", "v2")
+
+	// then
+	if byVersion.Code != http.StatusOK || byInstance.Code != http.StatusOK {
+		t.Fatalf("got %v, %v, want %v", byVersion.Code, byInstance.Code, http.StatusOK)
+	}
+	var versionResp GetHostStatusesResponse
+	if err := json.Unmarshal(byVersion.Body.Bytes(), &versionResp); err != nil {
+		t.Fatal(err)
+	}
+	if len(versionResp.HostStatuses) != 1 || versionResp.HostStatuses[0].InstanceID != "c" {
+		t.Fatalf("version weight: got %+v, want only instance c", versionResp.HostStatuses)
+	}
+	if tooHeavy.Code != http.StatusBadRequest {
+		t.Fatalf("too heavy: got %v, want %v", tooHeavy.Code, http.StatusBadRequest)
+	}
+	if unknownVersion.Code != http.StatusNotFound {
+		t.Fatalf("unknown version: got %v, want %v", unknownVersion.Code, http.StatusNotFound)
+	}
+
+	weights := make(map[string]int)
+	for _, hostStatus := range store.Get("one") {
+		weights[hostStatus.InstanceID] = hostStatus.Weight
+	}
+	want := map[string]int{"a": 0, "b": DefaultWeight, "c": 5}
+	for instanceID, weight := range want {
+		if weights[instanceID] != weight {
+			t.Fatalf("weights: got %v, want %v", weights, want)
+		}
+	}
+}
+
+func Test_GetHostStatuses_WeightedStrategy(t *testing.T) {
+	// given
+	store := NewStore()
+	lookupHandler := NewHandler(store)
+	for _, instanceID := range []string{"a", "b", "c"} {
+		if _, _, err := store.register("one", Instance{ID: instanceID, Host: instanceID + ":8080"}, "", systemActor); err != nil {
+			t.Fatal(err)
+		}
+		store.Put("one", instanceID, Healthy)
+	}
+	store.Put("one", "c", Down)
+
+	lookup := func(query string) (int, GetHostStatusesResponse) {
+		recorder := httptest.NewRecorder()
+		lookupHandler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v2/services/one/instances?"+query, nil))
+		var resp GetHostStatusesResponse
+		_ = json.Unmarshal(recorder.Body.Bytes(), &resp)
+		return recorder.Code, resp
+	}
+
+	// when
+	oneCode, one := lookup("strategy=weighted")
+	listCode, list := lookup("strategy=weighted&count=5")
+	badStrategy, _ := lookup("strategy=fastest")
+	badCount, _ := lookup("strategy=weighted&count=0")
+
+	// then
+	if oneCode != http.StatusOK || len(one.HostStatuses) != 1 {
+		t.Fatalf("single pick: got %v with %d instances, want %v with 1", oneCode, len(one.HostStatuses), http.StatusOK)
+	}
+	if listCode != http.StatusOK || len(list.HostStatuses) != 2 {
+		t.Fatalf("ordered list: got %v with %d instances, want %v with 2 healthy", listCode, len(list.HostStatuses), http.StatusOK)
+	}
+	if badStrategy != http.StatusBadRequest || badCount != http.StatusBadRequest {
+		t.Fatalf("bad requests: got %v, %v, want %v", badStrategy, badCount, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,39 @@
+package registry
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const zoneHeader = "X-Eureka-Zone"
+
+func callerZone(request *http.Request) string {
+	if zone := request.URL.Query().Get("zone"); zone != "" {
+		return zone
+	}
+	return request.Header.Get(zoneHeader)
+}
+
+func rankByZone(hostStatuses []HostStatus, zone string) {
+	slices.SortFunc(hostStatuses, func(a, b HostStatus) int {
+		return cmp.Or(
+			cmp.Compare(zoneRank(a, zone), zoneRank(b, zone)),
+			strings.Compare(a.InstanceID, b.InstanceID),
+		)
+	})
+}
+
+func zoneRank(hostStatus HostStatus, zone string) int {
+	switch {
+	case hostStatus.Status == Degraded:
+		return 2
+	case hostStatus.Status != Healthy:
+		return 3
+	case zone != "" && hostStatus.Zone == zone:
+		return 0
+	default:
+		return 1
+	}
+}
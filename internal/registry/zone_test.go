@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func Test_GetHostStatuses_ZoneAffinity(t *testing.T) {
+	// given
+	store := NewStore()
+	handler := NewHandler(store)
+	instances := []Instance{
+		{ID: "a", Host: "10.0.0.1:8080", Zone: "zone-b"},
+		{ID: "b", Host: "10.0.0.2:8080", Zone: "zone-a"},
+		{ID: "c", Host: "10.0.0.3:8080", Zone: "zone-a"},
+		{ID: "d", Host: "10.0.0.4:8080", Zone: "zone-b"},
+	}
+	for _, instance := range instances {
+		if _, _, err := store.register("one", instance, "", systemActor); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Put("one", "a", Healthy)
+	store.Put("one", "b", Healthy)
+	store.Put("one", "d", Healthy)
+
+	// when
+	byQuery := decodeHostStatusesResponse(t, doAppsRequest(handler, "/service-id/one?zone=zone-a"))
+	byHeader := decodeHostStatusesResponse(t, doZoneRequest(handler, "/service-id/one", "zone-b"))
+	noZone := decodeHostStatusesResponse(t, doAppsRequest(handler, "/service-id/one"))
+
+	// then
+	if got, want := instanceIDs(byQuery.HostStatuses), []string{"b", "a", "d", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("zone-a order: got %v, want %v", got, want)
+	}
+	if got, want := instanceIDs(byHeader.HostStatuses), []string{"a", "d", "b", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("zone-b order: got %v, want %v", got, want)
+	}
+	if got, want := instanceIDs(noZone.HostStatuses), []string{"a", "b", "d", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("no zone order: got %v, want %v", got, want)
+	}
+}
+
+func doZoneRequest(handler http.Handler, target string, zone string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	request.Header.Set(zoneHeader, zone)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func instanceIDs(hostStatuses []HostStatus) []string {
+	ids := make([]string, 0, len(hostStatuses))
+	for _, hostStatus := range hostStatuses {
+		ids = append(ids, hostStatus.InstanceID)
+	}
+	return ids
+}
@@ -0,0 +1,165 @@
+"use strict";
+
+const servicesElement = document.getElementById("services");
+const summaryElement = document.getElementById("summary");
+const connectionElement = document.getElementById("connection");
+const serviceTemplate = document.getElementById("service-template");
+
+let refreshTimer = null;
+
+async function refresh() {
+    const response = await fetch("/v2/services");
+    if (!response.ok) {
+        summaryElement.textContent = `Failed to load services: ${response.status}`;
+        return;
+    }
+    const body = await response.json();
+    render(body.services || {});
+}
+
+function scheduleRefresh() {
+    if (refreshTimer !== null) {
+        return;
+    }
+    refreshTimer = setTimeout(() => {
+        refreshTimer = null;
+        refresh();
+    }, 250);
+}
+
+function render(services) {
+    const serviceIDs = Object.keys(services).sort();
+    const instanceCount = serviceIDs.reduce((count, id) => count + services[id].length, 0);
+    summaryElement.textContent = `${serviceIDs.length} services, ${instanceCount} instances`;
+
+    servicesElement.replaceChildren(...serviceIDs.map(id => renderService(id, services[id])));
+}
+
+function renderService(serviceID, instances) {
+    const element = serviceTemplate.content.firstElementChild.cloneNode(true);
+    element.querySelector("h2").textContent = serviceID;
+
+    const rows = instances
+        .slice()
+        .sort((a, b) => a.instance_id.localeCompare(b.instance_id))
+        .map(instance => renderInstance(serviceID, instance));
+    element.querySelector("tbody").replaceChildren(...rows);
+
+    return element;
+}
+
+function renderInstance(serviceID, instance) {
+    const row = document.createElement("tr");
+    const lastCheck = instance.last_check || {};
+    const override = instance.override;
+
+    row.append(
+        cell(instance.instance_id),
+        cell(instance.host),
+        cell(instance.status, `status-${instance.status}`),
+        cell(instance.health, `status-${instance.health}`),
+        cell(override ? formatOverride(override) : ""),
+        cell(formatMetadata(instance.metadata)),
+        cell(lastCheck.checked_at ? new Date(lastCheck.checked_at).toLocaleString() : "never"),
+        cell(lastCheck.error || formatEjection(instance.ejection) || formatDrain(instance.drain) || formatComponents(lastCheck.components), "error"),
+        actions(serviceID, instance),
+    );
+
+    return row;
+}
+
+function cell(text, className) {
+    const element = document.createElement("td");
+    element.textContent = text;
+    if (className) {
+        element.className = className;
+    }
+    return element;
+}
+
+function actions(serviceID, instance) {
+    const element = document.createElement("td");
+    const instancePath = `${encodeURIComponent(serviceID)}/instances/${encodeURIComponent(instance.instance_id)}`;
+
+    if (instance.override) {
+        element.append(button("Clear override", () =>
+            send("DELETE", `/admin/services/${instancePath}/override`)));
+    } else {
+        element.append(button("Out of service", () =>
+            send("PUT", `/admin/services/${instancePath}/override`, {status: "out_of_service"})));
+    }
+    element.append(button("Deregister", () => {
+        if (confirm(`Deregister ${instance.instance_id} from ${serviceID}?`)) {
+            return send("DELETE", `/v2/services/${instancePath}`);
+        }
+    }));
+
+    return element;
+}
+
+function button(label, onClick) {
+    const element = document.createElement("button");
+    element.textContent = label;
+    element.addEventListener("click", onClick);
+    return element;
+}
+
+async function send(method, path, body) {
+    const response = await fetch(path, {
+        method: method,
+        headers: body ? {"Content-Type": "application/json"} : {},
+        body: body ? JSON.stringify(body) : undefined,
+    });
+    if (!response.ok) {
+        alert(`${method} ${path} failed: ${response.status} ${await response.text()}`);
+    }
+    scheduleRefresh();
+}
+
+function formatOverride(override) {
+    if (!override.expires_at) {
+        return override.status;
+    }
+    return `${override.status} until ${new Date(override.expires_at).toLocaleString()}`;
+}
+
+function formatMetadata(metadata) {
+    return Object.entries(metadata || {})
+        .map(([key, value]) => `${key}=${value}`)
+        .join(", ");
+}
+
+function formatEjection(ejection) {
+    return ejection ? `ejected until ${new Date(ejection.until).toLocaleTimeString()}: ${ejection.reason}` : "";
+}
+
+function formatDrain(drain) {
+    return drain ? `draining, removed at ${new Date(drain.until).toLocaleTimeString()}` : "";
+}
+
+function formatComponents(components) {
+    return Object.entries(components || {})
+        .filter(([, component]) => component.status !== "healthy")
+        .map(([name, component]) => `${name}: ${component.status}`)
+        .join(", ");
+}
+
+function connect() {
+    const source = new EventSource("/v2/events");
+    source.onopen = () => {
+        connectionElement.textContent = "live";
+        connectionElement.className = "connected";
+        scheduleRefresh();
+    };
+    source.onerror = () => {
+        connectionElement.textContent = "reconnecting";
+        connectionElement.className = "disconnected";
+    };
+    for (const type of ["registered", "updated", "status_changed", "removed"]) {
+        source.addEventListener(type, scheduleRefresh);
+    }
+}
+
+refresh();
+connect();
+setInterval(refresh, 15000);
@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"slices"
+	"sync"
+	"time"
+)
+
+type DeadLetter struct {
+	SubscriptionID string         `json:"subscription_id"`
+	Event          registry.Event `json:"event"`
+	Attempts       int            `json:"attempts"`
+	Error          string         `json:"error"`
+	FailedAt       time.Time      `json:"failed_at"`
+}
+
+type deadLetters struct {
+	entries []DeadLetter
+	size    int
+	lock    sync.Mutex
+}
+
+// add keeps nothing when size is 0, which disables the dead-letter list.
+func (d *deadLetters) add(deadLetter DeadLetter) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.size <= 0 {
+		return
+	}
+	if len(d.entries) == d.size {
+		d.entries = d.entries[1:]
+	}
+	d.entries = append(d.entries, deadLetter)
+}
+
+func (d *deadLetters) list() []DeadLetter {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return slices.Clone(d.entries)
+}
+
+func newDeadLetters(size int) *deadLetters {
+	return &deadLetters{
+		entries: make([]DeadLetter, 0, size),
+		size:    size,
+		lock:    sync.Mutex{},
+	}
+}
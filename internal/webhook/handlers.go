@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"log/slog"
+	"net/http"
+)
+
+type CreateSubscriptionRequest struct {
+	URL        string               `json:"url"`
+	Secret     string               `json:"secret,omitempty"`
+	Namespaces []string             `json:"namespaces,omitempty"`
+	ServiceIDs []string             `json:"service_ids,omitempty"`
+	EventTypes []registry.EventType `json:"event_types,omitempty"`
+}
+
+type CreateSubscriptionHandler struct {
+	manager *Manager
+}
+
+func (h CreateSubscriptionHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var createReq CreateSubscriptionRequest
+	if err := json.NewDecoder(request.Body).Decode(&createReq); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.manager.Add(Subscription{
+		URL:        createReq.URL,
+		Secret:     createReq.Secret,
+		Namespaces: createReq.Namespaces,
+		ServiceIDs: createReq.ServiceIDs,
+		EventTypes: createReq.EventTypes,
+	})
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The secret is only returned once, so that subscribers can verify signatures.
+	writeJSON(writer, subscription, http.StatusCreated)
+}
+
+type ListSubscriptionsHandler struct {
+	manager *Manager
+}
+
+func (h ListSubscriptionsHandler) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	writeJSON(writer, h.manager.List(), http.StatusOK)
+}
+
+type GetSubscriptionHandler struct {
+	manager *Manager
+}
+
+func (h GetSubscriptionHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	subscription, ok := h.manager.Get(request.PathValue("subscriptionID"))
+	if !ok {
+		http.Error(writer, ErrNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(writer, subscription, http.StatusOK)
+}
+
+type DeleteSubscriptionHandler struct {
+	manager *Manager
+}
+
+func (h DeleteSubscriptionHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if err := h.manager.Remove(request.PathValue("subscriptionID")); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			http.Error(writer, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+type DeadLettersHandler struct {
+	manager *Manager
+}
+
+func (h DeadLettersHandler) ServeHTTP(writer http.ResponseWriter, _ *http.Request) {
+	writeJSON(writer, h.manager.DeadLetters(), http.StatusOK)
+}
+
+func writeJSON(writer http.ResponseWriter, body any, status int) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(body); err != nil {
+		slog.Error("Failed to write response", "err:", err)
+	}
+}
+
+func NewHandler(manager *Manager) http.Handler {
+	mux := http.NewServeMux()
+
+	createSubscriptionHandler := &CreateSubscriptionHandler{manager: manager}
+	listSubscriptionsHandler := &ListSubscriptionsHandler{manager: manager}
+	getSubscriptionHandler := &GetSubscriptionHandler{manager: manager}
+	deleteSubscriptionHandler := &DeleteSubscriptionHandler{manager: manager}
+	deadLettersHandler := &DeadLettersHandler{manager: manager}
+
+	mux.Handle("POST /admin/webhooks", createSubscriptionHandler)
+	mux.Handle("GET /admin/webhooks", listSubscriptionsHandler)
+	mux.Handle("GET /admin/webhooks/dead-letters", deadLettersHandler)
+	mux.Handle("GET /admin/webhooks/{subscriptionID}", getSubscriptionHandler)
+	mux.Handle("DELETE /admin/webhooks/{subscriptionID}", deleteSubscriptionHandler)
+
+	return mux
+}
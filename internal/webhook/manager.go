@@ -0,0 +1,284 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"fmt"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type EventSource interface {
+	Subscribe(buffer int, dropped func(registry.Event)) (<-chan registry.Event, func())
+	HasNamespace(namespace string) bool
+}
+
+type Policy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	QueueSize      int
+}
+
+type Manager struct {
+	source      EventSource
+	httpClient  *http.Client
+	policy      Policy
+	subscribers map[string]*subscriber
+	lock        sync.RWMutex
+	deadLetters *deadLetters
+	now         func() time.Time
+}
+
+type subscriber struct {
+	subscription Subscription
+	queue        chan registry.Event
+	cancel       context.CancelFunc
+	done         chan struct{}
+}
+
+func (m *Manager) Run(ctx context.Context) error {
+	events, cancel := m.source.Subscribe(m.policy.QueueSize, m.dropped)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.stopAll()
+			return ctx.Err()
+		case event := <-events:
+			m.dispatch(event)
+		}
+	}
+}
+
+func (m *Manager) dispatch(event registry.Event) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	for _, sub := range m.subscribers {
+		if !sub.subscription.matches(event) {
+			continue
+		}
+		select {
+		case sub.queue <- event:
+		default:
+			m.deadLetters.add(DeadLetter{
+				SubscriptionID: sub.subscription.ID,
+				Event:          event,
+				Error:          "delivery queue is full",
+				FailedAt:       m.now(),
+			})
+		}
+	}
+}
+
+// dropped dead-letters an event the registry could not hand to Run, for every
+// subscription it would have been delivered to.
+func (m *Manager) dropped(event registry.Event) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	for _, sub := range m.subscribers {
+		if sub.subscription.matches(event) {
+			m.deadLetters.add(DeadLetter{
+				SubscriptionID: sub.subscription.ID,
+				Event:          event,
+				Error:          "event stream is full",
+				FailedAt:       m.now(),
+			})
+		}
+	}
+}
+
+func (m *Manager) Add(subscription Subscription) (Subscription, error) {
+	if err := subscription.complete(m.now()); err != nil {
+		return Subscription{}, err
+	}
+	for _, namespace := range subscription.Namespaces {
+		if !m.source.HasNamespace(namespace) {
+			return Subscription{}, fmt.Errorf("%w: %q is not configured", ErrInvalidNamespace, namespace)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := &subscriber{
+		subscription: subscription,
+		queue:        make(chan registry.Event, m.policy.QueueSize),
+		cancel:       cancel,
+		done:         make(chan struct{}),
+	}
+
+	m.lock.Lock()
+	m.subscribers[subscription.ID] = sub
+	m.lock.Unlock()
+
+	go m.deliverAll(ctx, sub)
+
+	return subscription, nil
+}
+
+func (m *Manager) Remove(id string) error {
+	m.lock.Lock()
+	sub, ok := m.subscribers[id]
+	delete(m.subscribers, id)
+	m.lock.Unlock()
+
+	if !ok {
+		return ErrNotFound
+	}
+	sub.cancel()
+	<-sub.done
+	return nil
+}
+
+func (m *Manager) Get(id string) (Subscription, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	sub, ok := m.subscribers[id]
+	if !ok {
+		return Subscription{}, false
+	}
+	return sub.subscription.redacted(), true
+}
+
+func (m *Manager) List() []Subscription {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	result := make([]Subscription, 0, len(m.subscribers))
+	for _, sub := range m.subscribers {
+		result = append(result, sub.subscription.redacted())
+	}
+	slices.SortFunc(result, func(a, b Subscription) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return result
+}
+
+func (m *Manager) DeadLetters() []DeadLetter {
+	return m.deadLetters.list()
+}
+
+func (m *Manager) stopAll() {
+	m.lock.Lock()
+	subs := m.subscribers
+	m.subscribers = make(map[string]*subscriber)
+	m.lock.Unlock()
+
+	for _, sub := range subs {
+		sub.cancel()
+		<-sub.done
+	}
+}
+
+func (m *Manager) deliverAll(ctx context.Context, sub *subscriber) {
+	defer close(sub.done)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-sub.queue:
+			m.deliverWithRetry(ctx, sub.subscription, event)
+		}
+	}
+}
+
+func (m *Manager) deliverWithRetry(ctx context.Context, subscription Subscription, event registry.Event) {
+	var err error
+	for attempt := 1; attempt <= m.policy.MaxAttempts; attempt++ {
+		if err = m.deliver(ctx, subscription, event); err == nil {
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("webhook delivery failed", "subscriptionID", subscription.ID, "version", event.Version, "attempt", attempt, "err", err)
+
+		if attempt == m.policy.MaxAttempts {
+			break
+		}
+		timer := time.NewTimer(m.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+
+	m.deadLetters.add(DeadLetter{
+		SubscriptionID: subscription.ID,
+		Event:          event,
+		Attempts:       m.policy.MaxAttempts,
+		Error:          err.Error(),
+		FailedAt:       m.now(),
+	})
+}
+
+func (m *Manager) deliver(ctx context.Context, subscription Subscription, event registry.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, subscription.sign(body))
+	req.Header.Set(EventHeader, string(event.Type))
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(event.Version, 10))
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (m *Manager) backoff(attempt int) time.Duration {
+	backoff := m.policy.InitialBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > m.policy.MaxBackoff {
+		return m.policy.MaxBackoff
+	}
+	return backoff
+}
+
+const (
+	SignatureHeader = "X-Eureka-Signature"
+	EventHeader     = "X-Eureka-Event"
+	DeliveryHeader  = "X-Eureka-Delivery"
+)
+
+func Verify(secret string, body []byte, signature string) bool {
+	expected := Subscription{Secret: secret}.sign(body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func NewManager(source EventSource, httpClient *http.Client, policy Policy, deadLetterSize int) *Manager {
+	return &Manager{
+		source:      source,
+		httpClient:  httpClient,
+		policy:      policy,
+		subscribers: make(map[string]*subscriber),
+		lock:        sync.RWMutex{},
+		deadLetters: newDeadLetters(deadLetterSize),
+		now:         time.Now,
+	}
+}
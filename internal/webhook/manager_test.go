@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeSource struct {
+	events chan registry.Event
+}
+
+func (f fakeSource) Subscribe(int, func(registry.Event)) (<-chan registry.Event, func()) {
+	return f.events, func() {}
+}
+
+func (f fakeSource) HasNamespace(namespace string) bool {
+	return namespace == registry.DefaultNamespace
+}
+
+func Test_Manager_DeliversSignedEventsInOrder(t *testing.T) {
+	// given
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan registry.Event, 8)
+	var attempts atomic.Int32
+	var secret string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := io.ReadAll(request.Body)
+		if !Verify(secret, body, request.Header.Get(SignatureHeader)) {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if attempts.Add(1) == 1 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event registry.Event
+		_ = json.Unmarshal(body, &event)
+		received <- event
+	}))
+	defer server.Close()
+
+	source := fakeSource{events: make(chan registry.Event, 8)}
+	manager := NewManager(source, server.Client(), Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, QueueSize: 8}, 8)
+	go func() { _ = manager.Run(ctx) }()
+
+	subscription, err := manager.Add(Subscription{URL: server.URL, ServiceIDs: []string{"one"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret = subscription.Secret
+
+	// when
+	source.events <- registry.Event{Version: 1, Type: registry.Registered, ServiceID: "one"}
+	source.events <- registry.Event{Version: 2, Type: registry.Registered, ServiceID: "two"}
+	source.events <- registry.Event{Version: 3, Type: registry.Updated, ServiceID: "one"}
+	source.events <- registry.Event{Version: 4, Type: registry.StatusChanged, ServiceID: "one"}
+	source.events <- registry.Event{Version: 5, Type: registry.Removed, ServiceID: "one"}
+
+	// then
+	for _, wantVersion := range []uint64{1, 4, 5} {
+		select {
+		case event := <-received:
+			if event.Version != wantVersion {
+				t.Fatalf("event version: got %d, want %d", event.Version, wantVersion)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event %d", wantVersion)
+		}
+	}
+	if deadLetters := manager.DeadLetters(); len(deadLetters) != 0 {
+		t.Fatalf("len(deadLetters) = %d, want 0", len(deadLetters))
+	}
+}
+
+func Test_Manager_RecordsDeadLetterAfterRetries(t *testing.T) {
+	// given
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	source := fakeSource{events: make(chan registry.Event, 8)}
+	manager := NewManager(source, server.Client(), Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond, QueueSize: 8}, 8)
+	go func() { _ = manager.Run(ctx) }()
+
+	subscription, err := manager.Add(Subscription{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	source.events <- registry.Event{Version: 7, Type: registry.Removed, ServiceID: "one"}
+
+	// then
+	deadline := time.Now().Add(5 * time.Second)
+	for len(manager.DeadLetters()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for dead letter")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	deadLetter := manager.DeadLetters()[0]
+	if deadLetter.SubscriptionID != subscription.ID || deadLetter.Event.Version != 7 || deadLetter.Attempts != 3 {
+		t.Fatalf("dead letter: got %+v, want subscription %s, version 7, 3 attempts", deadLetter, subscription.ID)
+	}
+	if got := attempts.Load(); got != 3 {
+		t.Fatalf("attempts = %d, want 3", got)
+	}
+}
+
+func Test_Subscription_RejectsInvalidInput(t *testing.T) {
+	// given
+	manager := NewManager(fakeSource{}, http.DefaultClient, Policy{MaxAttempts: 1, QueueSize: 1}, 1)
+
+	// when
+	_, badURL := manager.Add(Subscription{URL: "ftp://example.com"})
+	_, badType := manager.Add(Subscription{URL: "http://example.com", EventTypes: []registry.EventType{"exploded"}})
+
+	// then
+	if badURL == nil {
+		t.Fatal("expected error for invalid url")
+	}
+	if badType == nil {
+		t.Fatal("expected error for invalid event type")
+	}
+	if len(manager.List()) != 0 {
+		t.Fatalf("len(subscriptions) = %d, want 0", len(manager.List()))
+	}
+}
+
+func Test_Manager_DeadLettersEventsDroppedByTheRegistry(t *testing.T) {
+	// given
+	store := registry.NewStoreFrom(map[string]map[string]registry.Instance{
+		"one": {"a": {ID: "a", Host: "127.0.0.1:8080"}, "b": {ID: "b", Host: "127.0.0.1:8081"}},
+		"two": {"c": {ID: "c", Host: "127.0.0.1:8082"}},
+	})
+	manager := NewManager(store, http.DefaultClient, Policy{MaxAttempts: 1, QueueSize: 1}, 8)
+	disabled := NewManager(store, http.DefaultClient, Policy{MaxAttempts: 1, QueueSize: 1}, 0)
+	for _, m := range []*Manager{manager, disabled} {
+		if _, err := m.Add(Subscription{URL: "http://127.0.0.1:1/hook", ServiceIDs: []string{"one"}}); err != nil {
+			t.Fatal(err)
+		}
+		_, cancel := store.Subscribe(m.policy.QueueSize, m.dropped)
+		defer cancel()
+	}
+
+	// when
+	store.Put("one", "a", registry.Healthy)
+	store.Put("one", "b", registry.Healthy)
+	store.Put("two", "c", registry.Healthy)
+
+	// then
+	deadLetters := manager.DeadLetters()
+	if len(deadLetters) != 1 || deadLetters[0].Event.Instance.InstanceID != "b" || deadLetters[0].Error != "event stream is full" {
+		t.Fatalf("dead letters: got %+v, want the dropped event of b", deadLetters)
+	}
+	if got := disabled.DeadLetters(); len(got) != 0 {
+		t.Fatalf("disabled dead letters: got %+v, want none", got)
+	}
+}
+
+func Test_CreateSubscriptionHandler_Namespaces(t *testing.T) {
+	// given
+	namespaces := registry.Namespaces{
+		registry.DefaultNamespace: registry.NewStore(),
+		"team-a":                  registry.NewStore(registry.WithNamespace("team-a")),
+	}
+	manager := NewManager(namespaces, http.DefaultClient, Policy{MaxAttempts: 1, QueueSize: 1}, 1)
+	handler := NewHandler(manager)
+
+	create := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/admin/webhooks", strings.NewReader(body)))
+		return recorder
+	}
+
+	// when
+	created := create(`{"url":"http://127.0.0.1:1/hook","namespaces":["team-a"]}`)
+	unknown := create(`{"url":"http://127.0.0.1:1/hook","namespaces":["team-b"]}`)
+	invalid := create(`{"url":"http://127.0.0.1:1/hook","namespaces":["Team A"]}`)
+
+	// then
+	if created.Code != http.StatusCreated {
+		t.Fatalf("created: got %v, want %v: %s", created.Code, http.StatusCreated, created.Body)
+	}
+	var subscription Subscription
+	if err := json.Unmarshal(created.Body.Bytes(), &subscription); err != nil {
+		t.Fatal(err)
+	}
+	if len(subscription.Namespaces) != 1 || subscription.Namespaces[0] != "team-a" {
+		t.Fatalf("namespaces: got %v, want [team-a]", subscription.Namespaces)
+	}
+	if !subscription.matches(registry.Event{Type: registry.Registered, Namespace: "team-a"}) || subscription.matches(registry.Event{Type: registry.Registered}) {
+		t.Fatalf("subscription %+v does not match only team-a", subscription)
+	}
+	if unknown.Code != http.StatusBadRequest || invalid.Code != http.StatusBadRequest {
+		t.Fatalf("unknown and invalid namespaces: got %v, %v, want %v", unknown.Code, invalid.Code, http.StatusBadRequest)
+	}
+	if got := len(manager.List()); got != 1 {
+		t.Fatalf("len(subscriptions) = %d, want 1", got)
+	}
+}
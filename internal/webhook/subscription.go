@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/mat-sik/eureka-go/internal/registry"
+	"net/url"
+	"slices"
+	"time"
+)
+
+var (
+	ErrNotFound         = errors.New("subscription not found")
+	ErrInvalidURL       = errors.New("invalid webhook url")
+	ErrInvalidEventType = errors.New("invalid event type")
+	ErrInvalidNamespace = errors.New("invalid namespace")
+)
+
+var defaultEventTypes = []registry.EventType{registry.Registered, registry.Removed, registry.StatusChanged}
+
+type Subscription struct {
+	ID         string               `json:"id"`
+	URL        string               `json:"url"`
+	Secret     string               `json:"secret,omitempty"`
+	Namespaces []string             `json:"namespaces,omitempty"`
+	ServiceIDs []string             `json:"service_ids,omitempty"`
+	EventTypes []registry.EventType `json:"event_types"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
+
+func (s Subscription) matches(event registry.Event) bool {
+	namespace := event.Namespace
+	if namespace == "" {
+		namespace = registry.DefaultNamespace
+	}
+	if len(s.Namespaces) > 0 && !slices.Contains(s.Namespaces, namespace) {
+		return false
+	}
+	if len(s.ServiceIDs) > 0 && !slices.Contains(s.ServiceIDs, event.ServiceID) {
+		return false
+	}
+	return slices.Contains(s.EventTypes, event.Type)
+}
+
+func (s Subscription) redacted() Subscription {
+	s.Secret = ""
+	return s
+}
+
+func (s Subscription) sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(s.Secret))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Subscription) complete(now time.Time) error {
+	target, err := url.Parse(s.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: %q", ErrInvalidURL, s.URL)
+	}
+
+	for _, namespace := range s.Namespaces {
+		if !registry.ValidNamespace(namespace) {
+			return fmt.Errorf("%w: %q", ErrInvalidNamespace, namespace)
+		}
+	}
+
+	if len(s.EventTypes) == 0 {
+		s.EventTypes = defaultEventTypes
+	}
+	for _, eventType := range s.EventTypes {
+		if !validEventType(eventType) {
+			return fmt.Errorf("%w: %q", ErrInvalidEventType, eventType)
+		}
+	}
+
+	s.ID = randomHex(16)
+	if s.Secret == "" {
+		s.Secret = randomHex(32)
+	}
+	s.CreatedAt = now
+
+	return nil
+}
+
+func validEventType(eventType registry.EventType) bool {
+	switch eventType {
+	case registry.Registered, registry.Updated, registry.StatusChanged, registry.Removed:
+		return true
+	default:
+		return false
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}